@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultCacheTTL     = 7 * 24 * time.Hour
+	defaultCacheMaxSize = 50
+)
+
+func init() {
+	CacheCmd.AddCommand(cacheClearCmd)
+}
+
+var CacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local cache of AI responses",
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached AI responses",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := cacheDir()
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			errLog("failed to read cache directory: %v", err)
+		}
+		removed := 0
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+				continue
+			}
+			if err := os.Remove(path.Join(dir, entry.Name())); err != nil {
+				errLog("failed to remove %s: %v", entry.Name(), err)
+			}
+			removed++
+		}
+		successLog("Removed %d cached AI responses from %s", removed, dir)
+	},
+}
+
+// cacheEntry 是一条缓存的 AI 响应
+type cacheEntry struct {
+	Provider  string    `json:"provider"`
+	Model     string    `json:"model"`
+	CreatedAt time.Time `json:"created_at"`
+	Response  string    `json:"response"`
+}
+
+// responseCache 按 diff、提示词、后端和模型缓存 AI 的响应，保存在 ~/.gitx/cache 下，每条响应一个文件
+type responseCache struct {
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+}
+
+// cacheDir 返回缓存目录 ~/.gitx/cache
+func cacheDir() string {
+	return path.Join(path.Dir(getConfigFilePath()), "cache")
+}
+
+// newResponseCache 根据 ai.cache 创建缓存，ai.cache.disabled 为 true 时返回 nil
+func newResponseCache() *responseCache {
+	cfg := config.AI.Cache
+	if cfg.Disabled {
+		return nil
+	}
+	c := &responseCache{dir: cacheDir(), ttl: defaultCacheTTL, maxSize: defaultCacheMaxSize << 20}
+	if cfg.TTL > 0 {
+		c.ttl = time.Duration(cfg.TTL) * time.Hour
+	}
+	if cfg.MaxSize > 0 {
+		c.maxSize = int64(cfg.MaxSize) << 20
+	}
+	return c
+}
+
+// cacheKey 计算缓存的键，user 中已经包含了 diff
+func cacheKey(provider, model, system, user string) string {
+	h := sha256.New()
+	for _, s := range []string{provider, model, system, user} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *responseCache) file(key string) string {
+	return path.Join(c.dir, key+".json")
+}
+
+// get 返回未过期的缓存，过期的缓存会被删除
+func (c *responseCache) get(key string) (string, bool) {
+	data, err := os.ReadFile(c.file(key))
+	if err != nil {
+		return "", false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || time.Since(entry.CreatedAt) > c.ttl {
+		os.Remove(c.file(key))
+		return "", false
+	}
+	return entry.Response, true
+}
+
+// put 写入缓存，写入失败时只在调试模式下提示，不影响提交
+func (c *responseCache) put(key string, entry cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err == nil {
+		if err = os.MkdirAll(c.dir, 0700); err == nil {
+			err = os.WriteFile(c.file(key), data, 0600)
+		}
+	}
+	if err != nil {
+		if isDebug {
+			warningLog("write AI cache fail: %v", err)
+		}
+		return
+	}
+	c.prune()
+}
+
+// prune 删除过期的缓存，总大小超过限制时从最旧的开始删除
+func (c *responseCache) prune() {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	var files []os.FileInfo
+	var total int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.IsDir() || !strings.HasSuffix(info.Name(), ".json") {
+			continue
+		}
+		if time.Since(info.ModTime()) > c.ttl {
+			os.Remove(path.Join(c.dir, info.Name()))
+			continue
+		}
+		files = append(files, info)
+		total += info.Size()
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+	for _, info := range files {
+		if total <= c.maxSize {
+			break
+		}
+		os.Remove(path.Join(c.dir, info.Name()))
+		total -= info.Size()
+	}
+}
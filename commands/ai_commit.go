@@ -3,92 +3,19 @@ package commands
 import (
 	"context"
 	_ "embed"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"os"
+	"os/signal"
+	"slices"
 	"strings"
 	"time"
 
-	"github.com/openai/openai-go/v3"
 	"github.com/spf13/cobra"
-	"google.golang.org/genai"
 )
 
-// 转义 JSON 字符串，防止特殊字符导致 JSON 格式错误
-func escapeJSONString(s string) string {
-	b, _ := json.Marshal(s)
-	return string(b[1 : len(b)-1])
-}
-
-// ollama 调用本地 API，默认模型 qwen3.5:4b
-func ollama(systemPrompt string, userMessage string, model string) string {
-	if model == "" {
-		model = "qwen3.5:4b"
-	}
-	payload := `{"model":"` + model + `","messages":[{"role":"system","content":"` + escapeJSONString(systemPrompt) + `"},{"role":"user","content":"正文内容一律使用中文"},{"role":"user","content":"` + escapeJSONString(userMessage) + `"}]}`
-	resp, err := ollamaPost("http://localhost:11434/api/chat", payload)
-	if err != nil {
-		errLog("Ollama API error: %v", err)
-	}
-	return strings.TrimSpace(resp)
-}
-
-func ollamaPost(url, payload string) (string, error) {
-	req, err := http.NewRequest("POST", url, strings.NewReader(payload))
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	// Ollama /api/chat 返回流式 NDJSON，每行一个 JSON
-	var lastLine string
-	buf := make([]byte, 4096)
-	var lines []string
-	for {
-		n, err := resp.Body.Read(buf)
-		if n > 0 {
-			chunk := string(buf[:n])
-			for _, line := range strings.Split(chunk, "\n") {
-				line = strings.TrimSpace(line)
-				if line != "" {
-					lines = append(lines, line)
-				}
-			}
-		}
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return "", err
-		}
-	}
-	if len(lines) == 0 {
-		return "", fmt.Errorf("ollama: empty response")
-	}
-	lastLine = lines[len(lines)-1]
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("ollama http %d: %s", resp.StatusCode, lastLine)
-	}
-	type ollamaResp struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-	}
-	var r ollamaResp
-	if err := json.Unmarshal([]byte(lastLine), &r); err != nil {
-		return "", err
-	}
-	return r.Message.Content, nil
-}
-
 var (
 	aiConfirm   bool
 	autoAdd     bool
@@ -96,6 +23,23 @@ var (
 	aiAgent     string
 	ollamaModel string
 	version     string
+
+	aiModel       string
+	aiBaseURL     string
+	aiAPIKeyEnv   string
+	aiTemperature float64
+	aiMaxTokens   int
+	aiTimeout     time.Duration
+	maxChunks     int
+	noStream      bool
+	commitLang    string
+	noPush        bool
+	rebasePull    bool
+	noScope       bool
+	ticketIDFlags []string
+	splitCommits  bool
+	secretsFlag   string
+	noCache       bool
 )
 
 //go:embed prompts/github.prompt
@@ -104,197 +48,435 @@ var githubPrompt string
 //go:embed prompts/default.prompt
 var defaultPrompt string
 
+//go:embed prompts/summarize.prompt
+var summarizePrompt string
+
 var excludeFiles []string
 
 func init() {
+	bindAIFlags(AICommitCmd, "commit message")
 	AICommitCmd.Flags().BoolVarP(&aiConfirm, "yes", "y", false, "Auto confirm AI generated commit message")
 	AICommitCmd.Flags().BoolVarP(&autoAdd, "add", "a", false, "Auto git add . before generating commit message")
-	AICommitCmd.Flags().IntVarP(&limitLength, "limit", "l", 10000, "Set the maximum length of git diff to be processed")
-	AICommitCmd.Flags().StringVarP(&aiAgent, "agent", "", "openai", "Set the AI agent to use (openai|gemini|ollama)")
-	AICommitCmd.Flags().StringVarP(&ollamaModel, "ollama-model", "", "qwen3.5:4b", "Set the Ollama model name (default: qwen3.5:4b)")
-	AICommitCmd.Flags().StringSliceVarP(&excludeFiles, "exclude", "e", []string{}, "Comma-separated list of files to exclude from git diff")
+	AICommitCmd.Flags().IntVarP(&limitLength, "limit", "l", 10000, "Set the maximum length of git diff sent in one request, larger diffs are summarized in chunks")
+	AICommitCmd.Flags().IntVarP(&maxChunks, "max-chunks", "", 20, "Set the maximum number of chunks when summarizing a large diff")
+	AICommitCmd.Flags().StringVarP(&ollamaModel, "ollama-model", "", "", "Set the Ollama model name")
+	AICommitCmd.Flags().MarkDeprecated("ollama-model", "use --agent ollama --model <name> instead")
+	AICommitCmd.Flags().StringVarP(&aiBaseURL, "base-url", "", "", "Set the API base URL, e.g. an OpenAI-compatible endpoint")
+	AICommitCmd.Flags().StringVarP(&aiAPIKeyEnv, "api-key-env", "", "", "Set the environment variable holding the API key")
+	AICommitCmd.Flags().Float64VarP(&aiTemperature, "temperature", "", 0, "Set the sampling temperature")
+	AICommitCmd.Flags().IntVarP(&aiMaxTokens, "max-tokens", "", 0, "Set the maximum number of tokens to generate")
+	AICommitCmd.Flags().BoolVarP(&noStream, "no-stream", "", false, "Disable printing AI output while it is generated")
+	AICommitCmd.Flags().StringSliceVarP(&excludeFiles, "exclude", "e", []string{}, "Comma-separated list of pathspec globs excluded from the diff sent to AI and from --add, e.g. '*.lock', added to am.exclude")
 	AICommitCmd.Flags().StringVarP(&version, "version", "v", "", "Set the version for the commit message")
+	AICommitCmd.Flags().StringVarP(&secretsFlag, "secrets", "", "", "Set how secrets found in the diff are handled before sending it to AI (redact|block|off), overrides ai.secrets.mode (default redact)")
+	AICommitCmd.Flags().BoolVarP(&splitCommits, "split", "", false, "Ask the AI to split the staged changes into several logical commits")
+	AICommitCmd.Flags().BoolVarP(&noScope, "no-scope", "", false, "Do not infer the commit scope from the staged paths")
+	AICommitCmd.Flags().StringSliceVarP(&ticketIDFlags, "ticket", "", []string{}, "Set the issue IDs for the Refs/Closes trailer instead of reading them from the branch name")
+	AICommitCmd.Flags().BoolVarP(&noPush, "no-push", "", false, "Commit only, do not pull or push, overrides am.after_commit")
+	AICommitCmd.Flags().BoolVarP(&rebasePull, "rebase", "", false, "Use git pull --rebase before pushing, overrides am.after_commit")
 }
 
 var AICommitCmd = &cobra.Command{
-	Use:       "am [default|github]",
-	Short:     "Generate AI-based commit messages, then push to remote",
-	ValidArgs: []string{"default", "github"},
+	Use:   "am [default|github|<prompt>]",
+	Short: "Generate AI-based commit messages, then push to remote (see am.after_commit)",
+	Args:  cobra.MaximumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return promptNames(loadPromptTemplates()), cobra.ShellCompDirectiveNoFileComp
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		// 在生成提交信息之前检查 am.after_commit，避免提交之后才发现配置错误
+		mode := afterCommitMode()
+		if err := validateAfterCommitMode(mode); err != nil {
+			errLog("%v", err)
+		}
 		if autoAdd {
-			execCommand("git", "add", ".")
-			if len(excludeFiles) > 0 {
-				for _, file := range excludeFiles {
-					execCommand("git", "reset", file)
-				}
-			}
+			execCommand("git", append([]string{"add", "--", "."}, excludePathspecs()...)...)
 			successLog("Auto git add . executed.")
 		}
-		diff := execCommandWithOutput("git", "diff", "--cached")
+		diff := execCommandWithOutput("git", stagedDiffArgs()...)
 		if diff == "" {
 			if !autoAdd {
 				warningLog("use `git add .` first")
 			}
 			errLog("No changes detected.")
-		} else if len(diff) > limitLength {
-			warningLog(fmt.Sprintf("diff is too large (>%d characters), please commit manually", limitLength))
-			errLog("Diff too large.")
 		}
 
-		sp := defaultPrompt
-		var isGithub bool
-		if len(args) > 0 && args[0] == "github" {
-			sp = githubPrompt
-			isGithub = true
+		promptName := "default"
+		if len(args) > 0 {
+			promptName = args[0]
 		}
-		userMessage := "以下是 git diff 内容：\n" + diff
-		var commitMsg string
-		switch aiAgent {
-		case "openai":
-			commitMsg = openAI(sp, userMessage)
-		case "gemini":
-			commitMsg = gemini(sp, userMessage)
-		case "ollama":
-			commitMsg = ollama(sp, userMessage, ollamaModel)
-		default:
-			errLog("Unsupported AI agent: %s", aiAgent)
-		}
-		log.Println(commitMsg)
-
-		if !aiConfirm {
-			log.Print("Do you want to use this commit message and then push? (y/n): ")
-			var input string
-			fmt.Scanln(&input)
-			if strings.ToLower(input) != "y" {
-				warningLog("Commit aborted.")
-				return
-			}
+		tmpl, err := findPrompt(promptName)
+		if err != nil {
+			errLog("%v", err)
 		}
-		commitArgs := formatCommitMessage(commitMsg, isGithub)
-		if version != "" {
-			commitArgs = append(commitArgs, "-m", fmt.Sprintf("version: %s", version))
+		data := collectPromptData(languageName(commitLanguage(cmd)))
+		tickets, err := commitTickets(&data)
+		if err != nil {
+			errLog("%v", err)
 		}
-		execCommand("git", commitArgs...)
-		successLog("Committed with AI-generated message.")
-		currentBranch := execCommandWithOutput("git", "rev-parse", "--abbrev-ref", "HEAD")
-		cur := strings.TrimSpace(currentBranch)
-		// pull first, auto merge
-		if remoteBranchExists(".", cur) {
-			execCommand("git", "pull", "--no-edit")
-			execCommand("git", "push")
+		gen := newAIGenerator(cmd)
+		if splitCommits {
+			err = splitAndCommit(gen, tmpl, promptName, data, tickets)
 		} else {
-			execCommand("git", "push", "--set-upstream", "origin", cur)
+			err = commitDiff(gen, tmpl, promptName, data, tickets, diff)
+		}
+		if errors.Is(err, errCommitAborted) {
+			warningLog("Commit aborted.")
+			return
+		}
+		if err != nil {
+			errLog("%v", err)
+		}
+		if err := pushAfterCommit(mode); err != nil {
+			var conflict *pullConflictError
+			if errors.As(err, &conflict) {
+				conflict.report()
+				os.Exit(1)
+			}
+			errLog("Push fail, your commit is kept locally: %v", err)
+		}
+		if mode != afterCommitNone {
+			successLog("Pushed to remote repository.")
 		}
-		successLog("Pushed to remote repository.")
 	},
 }
 
-func gemini(systemPrompt string, userMessage string) string {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
-	defer cancel()
-	apiKey := os.Getenv("GEMINI_API_KEY")
-	if apiKey == "" {
-		errLog("GEMINI_API_KEY environment variable is not set. https://aistudio.google.com/app/api-keys")
-	}
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  apiKey,
-		Backend: genai.BackendGeminiAPI,
-	})
+// errCommitAborted 表示在菜单中放弃了提交
+var errCommitAborted = errors.New("commit aborted")
+
+// commitDiff 为暂存区中的 diff 生成提交信息，确认后提交
+func commitDiff(gen *aiGenerator, tmpl promptTemplate, promptName string, data promptData, tickets []string, diff string) error {
+	message, err := composeCommitMessage(gen, tmpl, promptName, data, tickets, diff, !aiConfirm)
 	if err != nil {
-		errLog("Failed to create Gemini client: %v", err)
+		return err
 	}
-	chat, err := client.Chats.Create(ctx, "gemini-2.5-flash", &genai.GenerateContentConfig{Temperature: genai.Ptr[float32](0.5)}, nil)
-	if err != nil {
-		errLog("Gemini API error: %v", err)
-	}
-	result, err := chat.SendMessage(ctx, genai.Part{
-		Text: systemPrompt,
-	}, genai.Part{
-		Text: "正文内容一律使用中文",
-	}, genai.Part{
-		Text: userMessage,
-	})
-	if err != nil {
-		errLog("Gemini API error: %v", err)
+	if err := commitWithMessage(message); err != nil {
+		return fmt.Errorf("git commit fail: %w", err)
 	}
-	commitMsg := strings.TrimSpace(result.Text())
-	return commitMsg
+	successLog("Committed with AI-generated message.")
+	return nil
 }
 
-func openAI(systemPrompt string, userMessage string) string {
-	if os.Getenv("OPENAI_API_KEY") == "" {
-		errLog("OPENAI_API_KEY environment variable is not set.")
-	}
-	client := openai.NewClient() // defaults to os.LookupEnv("OPENAI_API_KEY")
-	chatCompletion, err := client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(systemPrompt),
-			openai.UserMessage("正文内容一律使用中文"),
-			openai.UserMessage(userMessage),
-		},
-		Model: openai.ChatModelGPT5_1,
-	})
+// composeCommitMessage 为 diff 生成完整的提交信息，包括换行后的正文和 trailers，
+// interactive 为 true 时通过菜单确认、重新生成或编辑
+func composeCommitMessage(gen *aiGenerator, tmpl promptTemplate, promptName string, data promptData, tickets []string, diff string, interactive bool) (string, error) {
+	sp, err := renderPrompt(tmpl.Source, tmpl.Text, data)
 	if err != nil {
-		errLog("OpenAI API error: %v", err)
+		return "", fmt.Errorf("render prompt [%s] fail: %w", tmpl.Source, err)
 	}
-	commitMsg := strings.TrimSpace(chatCompletion.Choices[0].Message.Content)
-	return commitMsg
-}
-
-func formatCommitMessage(commitMsg string, isGithub bool) []string {
-	if isGithub {
-		msg := strings.Split(commitMsg, "\n")
-		args := []string{"commit"}
-		for _, line := range msg {
-			line = strings.TrimSpace(line)
-			if line != "" {
-				args = append(args, "-m", line)
+	// 内置的 default 模板不使用 scope，其余模板使用推断出的 scope 并在提交时强制生效
+	var scope string
+	if promptName != "default" && !noScope {
+		scope = data.Scope
+	}
+	// Ctrl-C 只取消正在进行的请求，菜单中恢复默认行为
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	userMessage, err := diffMessage(ctx, gen, diff, sp)
+	stop()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", errors.New("canceled")
+		}
+		return "", fmt.Errorf("summarize diff fail: %w", err)
+	}
+	if scope != "" {
+		successLog("Commit scope: %s", scope)
+		userMessage = fmt.Sprintf("Additional context for the changes:\nThe scope of this commit is `%s`, use it as the commit scope.\n\n%s", scope, userMessage)
+	}
+	validate := func(msg string) error {
+		_, err := normalizeCommitMessage(msg, scope)
+		return err
+	}
+	generate := func(guidance string) (string, string, error) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		user := userMessage
+		if guidance != "" {
+			user += "\n\n补充要求：" + guidance
+		}
+		msg, agent, err := gen.generate(ctx, sp, user, validate)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", agent, fmt.Errorf("canceled")
 			}
+			return "", agent, err
 		}
-		return args
-	}
-	// find multiple lines starting with feat:|test:|revert:|chore:|style:|refactor:|fix:
-	matchedPrefix := regexpSplitter.FindAllString(commitMsg, -1)
-	if len(matchedPrefix) == 0 {
-		errLog("No valid commit message prefix found in AI response.")
-	}
-	prefixCounter := make(map[string]int)
-	for _, prefix := range matchedPrefix {
-		if prefix != "\n" && prefix != "\r" {
-			prefix = strings.TrimSuffix(prefix, ":")
-			prefixCounter[prefix]++
+		commit, _ := normalizeCommitMessage(msg, scope)
+		if normalized := commit.String(); normalized != msg {
+			if gen.stream != nil {
+				warningLog("Commit message repaired:")
+				log.Println(normalized)
+			}
+			msg = normalized
 		}
+		return msg, agent, nil
+	}
+	commitMsg, usedAgent, err := generate("")
+	if err != nil {
+		return "", fmt.Errorf("generate commit message fail: %w", err)
 	}
-	var finalPrefix string
-	maxCount := 0
-	for prefix, count := range prefixCounter {
-		if count > maxCount {
-			maxCount = count
-			finalPrefix = prefix
+	successLog("Commit message generated by [%s]", usedAgent)
+	if gen.stream == nil {
+		log.Println(commitMsg)
+	}
+
+	if interactive {
+		session := &commitSession{gen: gen, generate: generate, validate: validate}
+		session.add(commitMsg, usedAgent)
+		var ok bool
+		if commitMsg, ok = session.run(); !ok {
+			return "", errCommitAborted
 		}
 	}
+	commit, err := normalizeCommitMessage(commitMsg, scope)
+	if err != nil {
+		return "", fmt.Errorf("invalid commit message: %w", err)
+	}
+	if version != "" {
+		setTrailer(commit, "Version", version)
+	}
+	if len(tickets) > 0 {
+		setTrailer(commit, ticketTrailer(), ticketTrailerValue(tickets))
+	}
+	message := buildCommitMessage(commit, bodyWrap())
 	if isDebug {
-		log.Printf("finalPrefix: [%s]\n", finalPrefix)
+		log.Printf("commit message:\n%s\n", message)
+	}
+	return message, nil
+}
+
+// afterCommitMode 返回提交后的行为，--no-push 和 --rebase 优先于 am.after_commit，默认 pull-push
+func afterCommitMode() string {
+	switch {
+	case noPush:
+		return afterCommitNone
+	case rebasePull:
+		return afterCommitPullRebasePush
+	case config.AM.AfterCommit != "":
+		return config.AM.AfterCommit
+	}
+	return afterCommitPullPush
+}
+
+// resolveProviderConfig 以配置文件为基础，用命令行显式传入的参数覆盖
+func resolveProviderConfig(cmd *cobra.Command, name string) ProviderConfig {
+	cfg := providerConfig(name)
+	flags := cmd.Flags()
+	if flags.Changed("ollama-model") && (name == "ollama" || cfg.Type == "ollama") {
+		cfg.Model = ollamaModel
+	}
+	if flags.Changed("model") {
+		cfg.Model = aiModel
+	}
+	if flags.Changed("base-url") {
+		cfg.BaseURL = aiBaseURL
+	}
+	if flags.Changed("api-key-env") {
+		cfg.APIKeyEnv = aiAPIKeyEnv
+	}
+	if flags.Changed("temperature") {
+		cfg.Temperature = &aiTemperature
 	}
+	if flags.Changed("max-tokens") {
+		cfg.MaxTokens = aiMaxTokens
+	}
+	return cfg
+}
 
-	splitCommitMsg := regexpSplitter.Split(commitMsg, -1)
-	filteredLines := []string{}
-	for _, line := range splitCommitMsg {
-		trimmedLine := strings.TrimSpace(line)
-		if trimmedLine != "" {
-			filteredLines = append(filteredLines, trimmedLine)
+// newAIGenerator 根据 --agent、ai.provider 和 ai.fallback 构建回退链
+func newAIGenerator(cmd *cobra.Command) *aiGenerator {
+	if !cmd.Flags().Changed("agent") && config.AI.Provider != "" {
+		aiAgent = config.AI.Provider
+	}
+	pinned := cmd.Flags().Changed("agent") || config.AI.Provider != ""
+	chain := providerChain(aiAgent, pinned)
+	return &aiGenerator{
+		chain: chain,
+		resolve: func(name string) ProviderConfig {
+			if name == chain[0] {
+				return resolveProviderConfig(cmd, name)
+			}
+			return providerConfig(name)
+		},
+		timeout: providerTimeout(cmd),
+		stream:  aiStream(),
+		cache:   aiCache(),
+		repo:    usageRepo(),
+	}
+}
+
+// aiCache 返回响应缓存，--no-cache 或 ai.cache.disabled 时不使用缓存
+func aiCache() *responseCache {
+	if noCache {
+		return nil
+	}
+	return newResponseCache()
+}
+
+// aiStream 返回流式输出的目标，--no-stream 时不输出
+func aiStream() io.Writer {
+	if noStream {
+		return nil
+	}
+	return os.Stderr
+}
+
+// prepareDiff 解析 diff，折叠忽略的文件、生成的文件和二进制文件并检查密钥，
+// 返回处理后的文件以及拼接后的 diff
+func prepareDiff(gen *aiGenerator, diff string) ([]fileDiff, string, error) {
+	parsed := parseDiff(diff)
+	files := collapseDiff(parsed, ignoreFiles(), generatedFiles(parsed))
+	if err := checkSecrets(files, gen.confirmed); err != nil {
+		return nil, "", err
+	}
+	var collapsed strings.Builder
+	for _, f := range files {
+		collapsed.WriteString(f.String())
+	}
+	return files, collapsed.String(), nil
+}
+
+// diffMessage 生成发送给 AI 的 diff 内容，超过 --limit 时按文件和 hunk 分段总结后再合并。
+// systems 是之后使用这段内容的每个请求的系统提示词，与分段总结的请求一起用于估算 token 数
+func diffMessage(ctx context.Context, gen *aiGenerator, diff string, systems ...string) (string, error) {
+	files, collapsed, err := prepareDiff(gen, diff)
+	if err != nil {
+		return "", err
+	}
+	if len(collapsed) <= limitLength {
+		user := "以下是 git diff 内容：\n" + collapsed
+		tokens := 0
+		for _, system := range systems {
+			tokens += estimateRequests(system, user)
+		}
+		if err := confirmTokens(gen, tokens, len(systems)); err != nil {
+			return "", err
+		}
+		return user, nil
+	}
+	chunks := chunkDiff(files, limitLength)
+	if len(chunks) > maxChunks {
+		return "", fmt.Errorf("diff is too large (%d chunks of %d characters, max %d), please commit manually or raise --max-chunks", len(chunks), limitLength, maxChunks)
+	}
+	users := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		users[i] = fmt.Sprintf("以下是第 %d/%d 段 git diff 内容：\n%s", i+1, len(chunks), chunk)
+	}
+	// 总结的长度无法预知，合并时的请求只计算系统提示词
+	tokens := estimateRequests(summarizePrompt, users...)
+	for _, system := range systems {
+		tokens += estimateTokens(system)
+	}
+	if err := confirmTokens(gen, tokens, len(chunks)+len(systems)); err != nil {
+		return "", err
+	}
+	warningLog("diff is too large (>%d characters), summarizing in %d chunks", limitLength, len(chunks))
+	summaries := make([]string, 0, len(chunks))
+	for i, user := range users {
+		summary, agent, err := gen.generate(ctx, summarizePrompt, user, nil)
+		if err != nil {
+			return "", fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
 		}
+		if isDebug {
+			log.Printf("chunk %d/%d summarized by [%s]:\n%s\n", i+1, len(chunks), agent, summary)
+		}
+		summaries = append(summaries, summary)
 	}
-	splitCommitMsg = filteredLines
+	return "以下是 git diff 按文件分段总结的改动摘要，请据此生成提交信息：\n" + strings.Join(summaries, "\n"), nil
+}
+
+// excludePatterns 返回不发送给 AI 的文件，包括 am.exclude 和 --exclude
+func excludePatterns() []string {
+	return append(slices.Clone(config.AM.Exclude), excludeFiles...)
+}
 
-	commitArgs := []string{}
-	commitArgs = append(commitArgs, "commit")
-	for idx, line := range splitCommitMsg {
-		line = strings.TrimSpace(line)
-		if idx == 0 && !strings.HasPrefix(line, finalPrefix) {
-			line = fmt.Sprintf("%s: %s", finalPrefix, line)
+// excludePathspecs 将排除规则转换为相对于仓库根目录的 pathspec，例如 *.lock 转换为 :(top,exclude)*.lock，
+// 已经以 : 开头的规则原样使用
+func excludePathspecs() []string {
+	var pathspecs []string
+	for _, pattern := range excludePatterns() {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
 		}
-		commitArgs = append(commitArgs, "-m", line)
+		if !strings.HasPrefix(pattern, ":") {
+			pattern = ":(top,exclude)" + pattern
+		}
+		pathspecs = append(pathspecs, pattern)
+	}
+	return pathspecs
+}
+
+// stagedDiffArgs 返回 git diff --cached 的参数，并排除 excludePatterns 中的文件
+func stagedDiffArgs(extra ...string) []string {
+	return append(append([]string{"diff", "--cached"}, extra...), excludeArgs()...)
+}
+
+// excludeArgs 返回排除 excludePatterns 中文件的 pathspec 参数，没有排除规则时为空
+func excludeArgs() []string {
+	pathspecs := excludePathspecs()
+	if len(pathspecs) == 0 {
+		return nil
+	}
+	return append([]string{"--", ":/"}, pathspecs...)
+}
+
+// ignoreFiles 返回需要折叠的文件列表，未配置 ai.ignore 时使用默认列表
+func ignoreFiles() []string {
+	if config.AI.Ignore != nil {
+		return config.AI.Ignore
+	}
+	return defaultIgnoreFiles
+}
+
+// bindAIFlags 注册所有 AI 命令共用的 --agent、--model、--lang、--timeout 和 --no-cache，output 是 --lang 作用的内容
+func bindAIFlags(cmd *cobra.Command, output string) {
+	cmd.Flags().StringVarP(&aiAgent, "agent", "", "openai", "Set the AI agent to use (openai|gemini|ollama or a registered provider)")
+	cmd.Flags().StringVarP(&aiModel, "model", "", "", "Set the model name, overrides ai.providers.<agent>.model")
+	cmd.Flags().StringVarP(&commitLang, "lang", "", "", "Set the language of the "+output+", e.g. zh, en, ja, overrides ai.language (default zh)")
+	cmd.Flags().DurationVarP(&aiTimeout, "timeout", "", 0, "Set the timeout of each AI agent, overrides ai.timeout (default 60s)")
+	cmd.Flags().BoolVarP(&noCache, "no-cache", "", false, "Do not read or write the local cache of AI responses")
+}
+
+// commitLanguage 返回提交信息的语言，优先使用 --lang，其次是 ai.language
+func commitLanguage(cmd *cobra.Command) string {
+	if cmd.Flags().Changed("lang") {
+		return commitLang
+	}
+	if config.AI.Language != "" {
+		return config.AI.Language
+	}
+	return defaultLanguage
+}
+
+// providerTimeout 返回单个后端的超时时间，优先使用 --timeout，其次是 ai.timeout
+func providerTimeout(cmd *cobra.Command) time.Duration {
+	if cmd.Flags().Changed("timeout") {
+		return aiTimeout
+	}
+	if config.AI.Timeout > 0 {
+		return time.Duration(config.AI.Timeout) * time.Second
+	}
+	return 60 * time.Second
+}
+
+// normalizeCommitMessage 按 Conventional Commits 解析并修复 AI 返回的提交信息，scope 不为空时强制使用该 scope，
+// 无法修复时返回错误并交给下一个后端
+func normalizeCommitMessage(commitMsg, scope string) (*conventionalCommit, error) {
+	if strings.TrimSpace(commitMsg) == "" {
+		return nil, fmt.Errorf("empty commit message")
+	}
+	commit, err := repairConventionalCommit(commitMsg, commitTypes())
+	if err != nil {
+		return nil, err
+	}
+	if scope != "" {
+		commit.Scope = scope
+	}
+	if err := checkSubjectLength(commit); err != nil {
+		return nil, err
 	}
-	return commitArgs
+	return commit, nil
 }
@@ -0,0 +1,235 @@
+package commands
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultIgnoreFiles 是默认折叠的文件，只向 AI 发送文件名和改动行数
+var defaultIgnoreFiles = []string{
+	"go.sum",
+	"package-lock.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"Cargo.lock",
+	"poetry.lock",
+	"composer.lock",
+	"Gemfile.lock",
+	"*.min.js",
+	"*.min.css",
+	"vendor/",
+	"node_modules/",
+}
+
+var regexpGeneratedHeader = regexp.MustCompile(`(?m)^[+ ].*(?:Code generated .* DO NOT EDIT|@generated)`)
+
+// fileDiff 是 git diff 中单个文件的改动
+type fileDiff struct {
+	Path   string
+	Header string
+	Hunks  []string
+	Binary bool
+}
+
+// String 还原为 git diff 格式
+func (f fileDiff) String() string {
+	return f.Header + strings.Join(f.Hunks, "")
+}
+
+// stat 统计新增和删除的行数
+func (f fileDiff) stat() (added, deleted int) {
+	for _, hunk := range f.Hunks {
+		for _, line := range strings.Split(hunk, "\n") {
+			if strings.HasPrefix(line, "+") {
+				added++
+			} else if strings.HasPrefix(line, "-") {
+				deleted++
+			}
+		}
+	}
+	return added, deleted
+}
+
+// parseDiff 将 git diff 按文件和 hunk 拆分
+func parseDiff(diff string) []fileDiff {
+	var files []fileDiff
+	var cur *fileDiff
+	var hunk strings.Builder
+	flushHunk := func() {
+		if cur != nil && hunk.Len() > 0 {
+			cur.Hunks = append(cur.Hunks, hunk.String())
+		}
+		hunk.Reset()
+	}
+	for _, line := range strings.SplitAfter(diff, "\n") {
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "diff --git ") {
+			flushHunk()
+			files = append(files, fileDiff{Path: diffPath(line)})
+			cur = &files[len(files)-1]
+			cur.Header = line
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			flushHunk()
+			hunk.WriteString(line)
+		case hunk.Len() > 0:
+			hunk.WriteString(line)
+		default:
+			if strings.HasPrefix(line, "Binary files ") || strings.HasPrefix(line, "GIT binary patch") {
+				cur.Binary = true
+			}
+			if strings.HasPrefix(line, "+++ ") && !strings.HasSuffix(strings.TrimSpace(line), "/dev/null") {
+				cur.Path = strings.TrimPrefix(unquotePath(strings.TrimSpace(strings.TrimPrefix(line, "+++ "))), "b/")
+			}
+			cur.Header += line
+		}
+	}
+	flushHunk()
+	return files
+}
+
+// diffPath 从 "diff --git a/x b/x" 中取出文件路径
+func diffPath(header string) string {
+	header = strings.TrimSpace(strings.TrimPrefix(header, "diff --git "))
+	if idx := strings.LastIndex(header, ` "b/`); idx >= 0 && strings.HasSuffix(header, `"`) {
+		return strings.TrimPrefix(unquotePath(header[idx+1:]), "b/")
+	}
+	if idx := strings.LastIndex(header, " b/"); idx >= 0 {
+		return header[idx+3:]
+	}
+	return header
+}
+
+// unquotePath 还原 git 在 core.quotepath 开启时为非 ASCII 和特殊字符的路径加上的引号和八进制转义，
+// 例如 "b/\344\270\255.go"
+func unquotePath(p string) string {
+	if !strings.HasPrefix(p, `"`) {
+		return p
+	}
+	if unquoted, err := strconv.Unquote(p); err == nil {
+		return unquoted
+	}
+	return p
+}
+
+// matchIgnore 判断文件是否匹配忽略列表，以 / 结尾的规则匹配目录，其余规则匹配完整路径或文件名
+func matchIgnore(patterns []string, file string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/") {
+			dir := strings.TrimSuffix(pattern, "/")
+			if strings.HasPrefix(file, dir+"/") || strings.Contains(file, "/"+dir+"/") {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(pattern, file); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(file)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// collapseDiff 将忽略列表中的文件、生成的文件和二进制文件折叠为一行说明
+func collapseDiff(files []fileDiff, ignore []string, generated map[string]bool) []fileDiff {
+	result := make([]fileDiff, 0, len(files))
+	for _, f := range files {
+		switch {
+		case f.Binary:
+			f.Header = fmt.Sprintf("# binary file changed: %s\n", f.Path)
+			f.Hunks = nil
+		case generated[f.Path] || matchIgnore(ignore, f.Path):
+			added, deleted := f.stat()
+			f.Header = fmt.Sprintf("# generated or vendored file changed: %s (+%d -%d)\n", f.Path, added, deleted)
+			f.Hunks = nil
+		}
+		result = append(result, f)
+	}
+	return result
+}
+
+// generatedFiles 找出生成的文件：.gitattributes 中标记了 linguist-generated，
+// 或者第一个 hunk 中带有 "Code generated ... DO NOT EDIT" 或 "@generated" 标记
+func generatedFiles(files []fileDiff) map[string]bool {
+	generated := map[string]bool{}
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, f.Path)
+		if len(f.Hunks) > 0 && regexpGeneratedHeader.MatchString(f.Hunks[0]) {
+			generated[f.Path] = true
+		}
+	}
+	if len(paths) == 0 {
+		return generated
+	}
+	// -z 输出为 "path\0attr\0value\0"，路径中的特殊字符不会被转义
+	args := append([]string{"--literal-pathspecs", "check-attr", "-z", "linguist-generated", "--"}, paths...)
+	output, err := runCommand("git", args...)
+	if err != nil {
+		return generated
+	}
+	fields := strings.Split(output, "\x00")
+	for i := 0; i+2 < len(fields); i += 3 {
+		if value := fields[i+2]; value == "set" || value == "true" {
+			generated[fields[i]] = true
+		}
+	}
+	return generated
+}
+
+// chunkDiff 将文件按 limit 打包成多个分段，单个文件超过 limit 时按 hunk 拆分，单个 hunk 超过 limit 时截断
+func chunkDiff(files []fileDiff, limit int) []string {
+	var chunks []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			chunks = append(chunks, cur.String())
+			cur.Reset()
+		}
+	}
+	add := func(s string) {
+		if cur.Len() > 0 && cur.Len()+len(s) > limit {
+			flush()
+		}
+		cur.WriteString(s)
+	}
+	for _, f := range files {
+		if s := f.String(); len(s) <= limit {
+			add(s)
+			continue
+		}
+		for _, hunk := range f.Hunks {
+			s := f.Header + hunk
+			if len(s) > limit {
+				s = truncateRunes(s, limit) + "\n# hunk truncated\n"
+			}
+			add(s)
+		}
+	}
+	flush()
+	return chunks
+}
+
+// truncateRunes 按字节截断，但不截断半个字符
+func truncateRunes(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit]
+}
@@ -0,0 +1,155 @@
+package commands
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleDiff = `diff --git a/main.go b/main.go
+index 3b18e51..a9c4f1d 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ package main
++import "fmt"
+
+ func main() {
+@@ -10,2 +11,2 @@ func main() {
+-	println("hi")
++	fmt.Println("hi")
+diff --git a/logo.png b/logo.png
+new file mode 100644
+index 0000000..b1e6722
+Binary files /dev/null and b/logo.png differ
+diff --git a/old name.txt b/new name.txt
+similarity index 90%
+rename from old name.txt
+rename to new name.txt
+--- a/old name.txt
++++ b/new name.txt
+@@ -1 +1 @@
+-a
++b
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+--- a/gone.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package gone
+`
+
+func TestParseDiff(t *testing.T) {
+	files := parseDiff(sampleDiff)
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	if want := []string{"main.go", "logo.png", "new name.txt", "gone.go"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("paths = %q, want %q", paths, want)
+	}
+	if len(files[0].Hunks) != 2 || !strings.HasPrefix(files[0].Hunks[1], "@@ -10,2 +11,2 @@") {
+		t.Errorf("main.go hunks = %q", files[0].Hunks)
+	}
+	if !files[1].Binary || len(files[1].Hunks) != 0 {
+		t.Errorf("logo.png = %+v", files[1])
+	}
+	if added, deleted := files[0].stat(); added != 2 || deleted != 1 {
+		t.Errorf("main.go stat = +%d -%d, want +2 -1", added, deleted)
+	}
+	var rebuilt strings.Builder
+	for _, f := range files {
+		rebuilt.WriteString(f.String())
+	}
+	if rebuilt.String() != sampleDiff {
+		t.Errorf("String() does not round-trip:\n%s", rebuilt.String())
+	}
+}
+
+func TestCollapseDiff(t *testing.T) {
+	files := collapseDiff(parseDiff(sampleDiff), []string{"*.txt"}, map[string]bool{"gone.go": true})
+	want := []string{
+		"",
+		"# binary file changed: logo.png\n",
+		"# generated or vendored file changed: new name.txt (+1 -1)\n",
+		"# generated or vendored file changed: gone.go (+0 -1)\n",
+	}
+	for i, f := range files {
+		if want[i] == "" {
+			if len(f.Hunks) != 2 {
+				t.Errorf("%s should not be collapsed", f.Path)
+			}
+			continue
+		}
+		if f.String() != want[i] {
+			t.Errorf("collapsed %s = %q, want %q", f.Path, f.String(), want[i])
+		}
+	}
+}
+
+func TestMatchIgnore(t *testing.T) {
+	patterns := []string{"go.sum", "*.min.js", "vendor/"}
+	for file, want := range map[string]bool{
+		"go.sum":                 true,
+		"tools/go.sum":           true,
+		"web/app.min.js":         true,
+		"vendor/x/y.go":          true,
+		"third/vendor/x.go":      true,
+		"vendored.go":            false,
+		"web/app.js":             false,
+		"docs/go.sum.md":         false,
+		"internal/vendorlist.go": false,
+	} {
+		if got := matchIgnore(patterns, file); got != want {
+			t.Errorf("matchIgnore(%q) = %v, want %v", file, got, want)
+		}
+	}
+}
+
+func TestChunkDiff(t *testing.T) {
+	file := func(name string, hunks ...string) fileDiff {
+		return fileDiff{Path: name, Header: "diff --git a/" + name + " b/" + name + "\n", Hunks: hunks}
+	}
+	hunk := func(n int) string {
+		return "@@ -1 +1 @@\n+" + strings.Repeat("x", n) + "\n"
+	}
+	small1, small2 := file("a.go", hunk(10)), file("b.go", hunk(10))
+	big := file("c.go", hunk(60), hunk(60))
+	huge := file("d.go", hunk(500))
+
+	chunks := chunkDiff([]fileDiff{small1, small2, big, huge}, 120)
+	if len(chunks) != 4 {
+		t.Fatalf("got %d chunks, want 4: %q", len(chunks), chunks)
+	}
+	// 小文件合并在同一段中
+	if chunks[0] != small1.String()+small2.String() {
+		t.Errorf("chunks[0] = %q", chunks[0])
+	}
+	// 超过 limit 的文件按 hunk 拆分，每段都带文件头
+	for _, c := range chunks[1:3] {
+		if !strings.HasPrefix(c, big.Header+"@@") {
+			t.Errorf("hunk chunk without header: %q", c)
+		}
+	}
+	// 超过 limit 的 hunk 被截断
+	if !strings.HasPrefix(chunks[3], huge.Header) || !strings.HasSuffix(chunks[3], "\n# hunk truncated\n") ||
+		len(chunks[3]) != 120+len("\n# hunk truncated\n") {
+		t.Errorf("chunks[3] = %q", chunks[3])
+	}
+	if got := truncateRunes("中文字符", 4); got != "中" {
+		t.Errorf("truncateRunes = %q, want 中", got)
+	}
+}
+
+func TestDiffPathQuoted(t *testing.T) {
+	for header, want := range map[string]string{
+		"diff --git a/main.go b/main.go\n":                                                    "main.go",
+		"diff --git a/old name.txt b/new name.txt\n":                                          "new name.txt",
+		`diff --git "a/\346\226\207\346\241\243.png" "b/\346\226\207\346\241\243.png"` + "\n": "文档.png",
+		`diff --git "a/tab\there" "b/tab\there"` + "\n":                                       "tab\there",
+	} {
+		if got := diffPath(header); got != want {
+			t.Errorf("diffPath(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+func init() {
+	RegisterProvider("gemini", newGeminiProvider)
+}
+
+type geminiProvider struct {
+	apiKey string
+	cfg    ProviderConfig
+}
+
+func newGeminiProvider(cfg ProviderConfig) (Provider, error) {
+	apiKey, env := cfg.apiKey("GEMINI_API_KEY")
+	if env == "" {
+		return nil, fmt.Errorf("api_key_env must be set when base_url is set")
+	}
+	if apiKey == "" {
+		return nil, fmt.Errorf("%s environment variable is not set. https://aistudio.google.com/app/api-keys", env)
+	}
+	if cfg.Model == "" {
+		cfg.Model = "gemini-2.5-flash"
+	}
+	return &geminiProvider{apiKey: apiKey, cfg: cfg}, nil
+}
+
+func (p *geminiProvider) chat(ctx context.Context) (*genai.Chat, error) {
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:      p.apiKey,
+		Backend:     genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{BaseURL: p.cfg.BaseURL},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gemini: create client: %w", err)
+	}
+	contentConfig := &genai.GenerateContentConfig{Temperature: genai.Ptr[float32](0.5)}
+	if p.cfg.Temperature != nil {
+		contentConfig.Temperature = genai.Ptr(float32(*p.cfg.Temperature))
+	}
+	if p.cfg.MaxTokens > 0 {
+		contentConfig.MaxOutputTokens = int32(p.cfg.MaxTokens)
+	}
+	chat, err := client.Chats.Create(ctx, p.cfg.Model, contentConfig, nil)
+	if err != nil {
+		return nil, fmt.Errorf("gemini: %w", err)
+	}
+	return chat, nil
+}
+
+func (p *geminiProvider) parts(system, user string) []genai.Part {
+	return []genai.Part{
+		{Text: system},
+		{Text: user},
+	}
+}
+
+func (p *geminiProvider) Generate(ctx context.Context, system, user string) (string, error) {
+	chat, err := p.chat(ctx)
+	if err != nil {
+		return "", err
+	}
+	result, err := chat.SendMessage(ctx, p.parts(system, user)...)
+	if err != nil {
+		return "", fmt.Errorf("gemini: %w", err)
+	}
+	p.recordUsage(ctx, result)
+	return strings.TrimSpace(result.Text()), nil
+}
+
+// recordUsage 上报 usageMetadata，思考消耗的 token 计入输出
+func (p *geminiProvider) recordUsage(ctx context.Context, result *genai.GenerateContentResponse) {
+	if result == nil || result.UsageMetadata == nil {
+		return
+	}
+	meta := result.UsageMetadata
+	recordUsage(ctx, tokenUsage{
+		Model:        p.cfg.Model,
+		InputTokens:  int(meta.PromptTokenCount),
+		OutputTokens: int(meta.CandidatesTokenCount + meta.ThoughtsTokenCount),
+	})
+}
+
+func (p *geminiProvider) GenerateStream(ctx context.Context, system, user string, w io.Writer) (string, error) {
+	chat, err := p.chat(ctx)
+	if err != nil {
+		return "", err
+	}
+	var content strings.Builder
+	for result, err := range chat.SendMessageStream(ctx, p.parts(system, user)...) {
+		if err != nil {
+			return "", fmt.Errorf("gemini: %w", err)
+		}
+		p.recordUsage(ctx, result)
+		text := result.Text()
+		content.WriteString(text)
+		io.WriteString(w, text)
+	}
+	return strings.TrimSpace(content.String()), nil
+}
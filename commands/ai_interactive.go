@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+)
+
+const (
+	actionAccept     = "Accept"
+	actionRegenerate = "Regenerate"
+	actionGuidance   = "Regenerate with guidance"
+	actionEdit       = "Edit in $EDITOR"
+	actionSwitch     = "Switch AI agent"
+	actionHistory    = "Pick a previous message"
+	actionAbort      = "Abort"
+)
+
+// commitCandidate 是一次生成的提交信息
+type commitCandidate struct {
+	Message string
+	Agent   string
+}
+
+// commitSession 保存本次 gitx am 生成过的提交信息，用于交互式地重新生成、编辑或者切换后端
+type commitSession struct {
+	gen      *aiGenerator
+	generate func(guidance string) (string, string, error)
+	validate func(msg string) error
+	history  []commitCandidate
+	current  int
+}
+
+func (s *commitSession) add(msg, agent string) {
+	s.history = append(s.history, commitCandidate{Message: msg, Agent: agent})
+	s.current = len(s.history) - 1
+}
+
+// run 循环展示菜单，直到接受或放弃，返回最终的提交信息
+func (s *commitSession) run() (string, bool) {
+	for {
+		cur := s.history[s.current]
+		items := []string{actionAccept, actionRegenerate, actionGuidance, actionEdit, actionSwitch}
+		if len(s.history) > 1 {
+			items = append(items, actionHistory)
+		}
+		items = append(items, actionAbort)
+		menu := promptui.Select{
+			Label: fmt.Sprintf("Use this commit message from [%s]?", cur.Agent),
+			Items: items,
+			Size:  len(items),
+		}
+		_, action, err := menu.Run()
+		if err != nil {
+			return "", false
+		}
+		switch action {
+		case actionAccept:
+			return cur.Message, true
+		case actionRegenerate:
+			s.regenerate("")
+		case actionGuidance:
+			guidancePrompt := promptui.Prompt{Label: "Extra guidance (e.g. mention the migration)"}
+			guidance, err := guidancePrompt.Run()
+			if err != nil || strings.TrimSpace(guidance) == "" {
+				continue
+			}
+			s.regenerate(guidance)
+		case actionEdit:
+			edited, err := editInEditor(cur.Message)
+			if err != nil {
+				warningLog("Edit commit message fail: %v", err)
+				continue
+			}
+			if err := s.validate(edited); err != nil {
+				warningLog("Edited commit message is invalid: %v", err)
+				continue
+			}
+			s.add(edited, "edited")
+			log.Println(edited)
+		case actionSwitch:
+			s.switchAgent()
+		case actionHistory:
+			s.pickHistory()
+		case actionAbort:
+			return "", false
+		}
+	}
+}
+
+// regenerate 重新生成提交信息，不读取缓存
+func (s *commitSession) regenerate(guidance string) {
+	s.gen.refresh = true
+	msg, agent, err := s.generate(guidance)
+	s.gen.refresh = false
+	if err != nil {
+		warningLog("Generate commit message fail: %v", err)
+		return
+	}
+	s.add(msg, agent)
+	successLog("Commit message generated by [%s]", agent)
+	if s.gen.stream == nil {
+		log.Println(msg)
+	}
+}
+
+// switchAgent 切换到指定的后端重新生成，不再使用回退链
+func (s *commitSession) switchAgent() {
+	names := providerNames()
+	for name := range config.AI.Providers {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	agentPrompt := promptui.Select{
+		Label: "Select AI agent",
+		Items: names,
+	}
+	_, name, err := agentPrompt.Run()
+	if err != nil {
+		return
+	}
+	s.gen.chain = []string{name}
+	s.regenerate("")
+}
+
+func (s *commitSession) pickHistory() {
+	labels := make([]string, len(s.history))
+	for i, c := range s.history {
+		subject := strings.SplitN(c.Message, "\n", 2)[0]
+		labels[i] = fmt.Sprintf("%d. [%s] %s", i+1, c.Agent, subject)
+	}
+	historyPrompt := promptui.Select{
+		Label:     "Select a previous commit message",
+		Items:     labels,
+		CursorPos: s.current,
+	}
+	idx, _, err := historyPrompt.Run()
+	if err != nil {
+		return
+	}
+	s.current = idx
+	log.Println(s.history[idx].Message)
+}
+
+// editInEditor 使用 $VISUAL 或 $EDITOR 编辑提交信息，以 # 开头的行会被忽略
+func editInEditor(msg string) (string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	f, err := os.CreateTemp("", "gitx-commit-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	content := msg + "\n\n# Edit the commit message above, lines starting with '#' are ignored.\n"
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return "", err
+	}
+	f.Close()
+	// 通过 sh 执行，兼容 "code --wait" 这类带参数的编辑器
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", f.Name())
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	edited := strings.TrimSpace(strings.Join(lines, "\n"))
+	if edited == "" {
+		return "", errors.New("empty commit message")
+	}
+	return edited, nil
+}
@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+func init() {
+	RegisterProvider("ollama", newOllamaProvider)
+}
+
+const defaultOllamaHost = "http://localhost:11434"
+
+// ollamaProvider 调用 Ollama 的 /api/chat 接口，默认模型 qwen3.5:4b，
+// 地址依次取 base_url、OLLAMA_HOST 环境变量和 http://localhost:11434
+type ollamaProvider struct {
+	cfg ProviderConfig
+}
+
+func newOllamaProvider(cfg ProviderConfig) (Provider, error) {
+	if cfg.Model == "" {
+		cfg.Model = "qwen3.5:4b"
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = ollamaHost(os.Getenv("OLLAMA_HOST"))
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	return &ollamaProvider{cfg: cfg}, nil
+}
+
+// ollamaHost 兼容 OLLAMA_HOST 的写法，例如 "0.0.0.0:11434" 或 "http://gpu-box:11434"
+func ollamaHost(host string) string {
+	if host == "" {
+		return defaultOllamaHost
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return host
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  map[string]any  `json:"options,omitempty"`
+}
+
+// ollamaChunk 是 /api/chat 流式响应中的一行
+type ollamaChunk struct {
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// ollamaResult 是拼接所有分段后的完整响应
+type ollamaResult struct {
+	Content         string
+	DoneReason      string
+	PromptEvalCount int
+	EvalCount       int
+}
+
+func (p *ollamaProvider) Generate(ctx context.Context, system, user string) (string, error) {
+	return p.GenerateStream(ctx, system, user, io.Discard)
+}
+
+func (p *ollamaProvider) GenerateStream(ctx context.Context, system, user string, w io.Writer) (string, error) {
+	payload := ollamaRequest{
+		Model: p.cfg.Model,
+		Messages: []ollamaMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+		Stream: true,
+	}
+	if p.cfg.Temperature != nil || p.cfg.MaxTokens > 0 {
+		payload.Options = map[string]any{}
+		if p.cfg.Temperature != nil {
+			payload.Options["temperature"] = *p.cfg.Temperature
+		}
+		if p.cfg.MaxTokens > 0 {
+			payload.Options["num_predict"] = p.cfg.MaxTokens
+		}
+	}
+	result, err := ollamaChat(ctx, p.cfg.BaseURL+"/api/chat", payload, w)
+	if err != nil {
+		return "", fmt.Errorf("ollama: %w", err)
+	}
+	if isDebug {
+		log.Printf("ollama: done_reason=%s prompt_eval_count=%d eval_count=%d\n",
+			result.DoneReason, result.PromptEvalCount, result.EvalCount)
+	}
+	recordUsage(ctx, tokenUsage{Model: p.cfg.Model, InputTokens: result.PromptEvalCount, OutputTokens: result.EvalCount})
+	if result.DoneReason == "length" {
+		warningLog("ollama: response truncated after %d tokens, consider raising max_tokens", result.EvalCount)
+	}
+	return strings.TrimSpace(result.Content), nil
+}
+
+func ollamaChat(ctx context.Context, url string, payload ollamaRequest, w io.Writer) (*ollamaResult, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		var chunk ollamaChunk
+		if json.Unmarshal(data, &chunk) == nil && chunk.Error != "" {
+			return nil, fmt.Errorf("http %d: %s", resp.StatusCode, chunk.Error)
+		}
+		return nil, fmt.Errorf("http %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return decodeOllamaStream(resp.Body, w)
+}
+
+// decodeOllamaStream 解析 /api/chat 返回的 NDJSON，拼接每一行的 message.content 并实时写入 w，
+// 直到收到 done:true 的最后一行
+func decodeOllamaStream(r io.Reader, w io.Writer) (*ollamaResult, error) {
+	var result ollamaResult
+	var content strings.Builder
+	decoder := json.NewDecoder(r)
+	for {
+		var chunk ollamaChunk
+		if err := decoder.Decode(&chunk); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("stream ended before done, received %d characters", content.Len())
+			}
+			return nil, fmt.Errorf("decode stream: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, errors.New(chunk.Error)
+		}
+		content.WriteString(chunk.Message.Content)
+		io.WriteString(w, chunk.Message.Content)
+		if chunk.Done {
+			result.Content = content.String()
+			result.DoneReason = chunk.DoneReason
+			result.PromptEvalCount = chunk.PromptEvalCount
+			result.EvalCount = chunk.EvalCount
+			break
+		}
+	}
+	if strings.TrimSpace(result.Content) == "" {
+		return nil, fmt.Errorf("empty response (done_reason: %s)", result.DoneReason)
+	}
+	return &result, nil
+}
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recordedOllamaStream 是按 ollama /api/chat 的格式录制的流式响应
+const recordedOllamaStream = `{"model":"qwen3.5:4b","created_at":"2026-10-01T08:00:00.000Z","message":{"role":"assistant","content":"feat"},"done":false}
+{"model":"qwen3.5:4b","created_at":"2026-10-01T08:00:00.050Z","message":{"role":"assistant","content":"(ai): "},"done":false}
+{"model":"qwen3.5:4b","created_at":"2026-10-01T08:00:00.100Z","message":{"role":"assistant","content":"add ollama provider"},"done":false}
+{"model":"qwen3.5:4b","created_at":"2026-10-01T08:00:00.150Z","message":{"role":"assistant","content":""},"done_reason":"stop","done":true,"total_duration":151000000,"prompt_eval_count":412,"eval_count":9}
+`
+
+// replayOllama 启动一个按原样回放 body 的 /api/chat 服务，并记录收到的请求
+func replayOllama(t *testing.T, status int, body string) (*httptest.Server, *ollamaRequest) {
+	t.Helper()
+	var got ollamaRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("path = %s, want /api/chat", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(status)
+		for _, line := range strings.SplitAfter(body, "\n") {
+			w.Write([]byte(line))
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got
+}
+
+func TestOllamaChatJoinsChunks(t *testing.T) {
+	srv, req := replayOllama(t, http.StatusOK, recordedOllamaStream)
+	var streamed strings.Builder
+	result, err := ollamaChat(context.Background(), srv.URL+"/api/chat", ollamaRequest{Model: "qwen3.5:4b", Stream: true}, &streamed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "feat(ai): add ollama provider"; result.Content != want || streamed.String() != want {
+		t.Errorf("content = %q, streamed = %q, want %q", result.Content, streamed.String(), want)
+	}
+	if result.DoneReason != "stop" || result.PromptEvalCount != 412 || result.EvalCount != 9 {
+		t.Errorf("result = %+v, want done_reason stop, prompt_eval_count 412, eval_count 9", result)
+	}
+	if !req.Stream || req.Model != "qwen3.5:4b" {
+		t.Errorf("request = %+v", req)
+	}
+}
+
+func TestOllamaProviderReportsUsage(t *testing.T) {
+	srv, req := replayOllama(t, http.StatusOK, recordedOllamaStream)
+	temperature := 0.2
+	p, err := newOllamaProvider(ProviderConfig{BaseURL: srv.URL + "/", Temperature: &temperature, MaxTokens: 128})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, usage := withUsage(context.Background())
+	msg, err := p.Generate(ctx, "system", "diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "feat(ai): add ollama provider" {
+		t.Errorf("message = %q", msg)
+	}
+	if *usage != (tokenUsage{Model: "qwen3.5:4b", InputTokens: 412, OutputTokens: 9}) {
+		t.Errorf("usage = %+v", *usage)
+	}
+	if len(req.Messages) != 2 || req.Messages[0].Role != "system" || req.Messages[1].Content != "diff" {
+		t.Errorf("messages = %+v", req.Messages)
+	}
+	if req.Options["temperature"] != 0.2 || req.Options["num_predict"] != float64(128) {
+		t.Errorf("options = %+v", req.Options)
+	}
+}
+
+func TestOllamaChatErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{
+			name:   "error line in stream",
+			status: http.StatusOK,
+			body: `{"message":{"role":"assistant","content":"feat"},"done":false}
+{"error":"model runner has unexpectedly stopped"}
+`,
+			want: "model runner has unexpectedly stopped",
+		},
+		{
+			name:   "non-2xx with error body",
+			status: http.StatusNotFound,
+			body:   `{"error":"model \"qwen3.5:4b\" not found, try pulling it first"}`,
+			want:   `http 404: model "qwen3.5:4b" not found`,
+		},
+		{
+			name:   "non-2xx with plain body",
+			status: http.StatusBadGateway,
+			body:   "bad gateway",
+			want:   "http 502: bad gateway",
+		},
+		{
+			name:   "stream ends before done",
+			status: http.StatusOK,
+			body:   strings.Join(strings.Split(recordedOllamaStream, "\n")[:2], "\n") + "\n",
+			want:   "stream ended before done, received 10 characters",
+		},
+		{
+			name:   "done without content",
+			status: http.StatusOK,
+			body:   `{"message":{"role":"assistant","content":""},"done_reason":"length","done":true}` + "\n",
+			want:   "empty response (done_reason: length)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := replayOllama(t, tt.status, tt.body)
+			_, err := ollamaChat(context.Background(), srv.URL+"/api/chat", ollamaRequest{Stream: true}, io.Discard)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
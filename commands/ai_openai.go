@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+func init() {
+	RegisterProvider("openai", newOpenAIProvider)
+}
+
+// openAIProvider 使用 Chat Completions 协议，也可以通过 base_url 对接 vLLM 等兼容服务
+type openAIProvider struct {
+	client openai.Client
+	cfg    ProviderConfig
+}
+
+func newOpenAIProvider(cfg ProviderConfig) (Provider, error) {
+	apiKey, env := cfg.apiKey("OPENAI_API_KEY")
+	// 自建的兼容服务通常不校验 key，只有官方接口才强制要求
+	if apiKey == "" && cfg.BaseURL == "" {
+		return nil, fmt.Errorf("%s environment variable is not set", env)
+	}
+	// 总是显式设置 key，SDK 不会再从 OPENAI_API_KEY 读取默认值
+	opts := []option.RequestOption{option.WithAPIKey(apiKey)}
+	if apiKey == "" {
+		opts = append(opts, option.WithHeaderDel("authorization"))
+	}
+	if cfg.BaseURL != "" {
+		// 同样不能把 OPENAI_ORG_ID 和 OPENAI_PROJECT_ID 发送给第三方服务
+		opts = append(opts, option.WithBaseURL(cfg.BaseURL),
+			option.WithHeaderDel("OpenAI-Organization"), option.WithHeaderDel("OpenAI-Project"))
+	}
+	if cfg.Model == "" {
+		cfg.Model = openai.ChatModelGPT5_1
+	}
+	return &openAIProvider{
+		client: openai.NewClient(opts...),
+		cfg:    cfg,
+	}, nil
+}
+
+func (p *openAIProvider) params(system, user string) openai.ChatCompletionNewParams {
+	params := openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.SystemMessage(system),
+			openai.UserMessage(user),
+		},
+		Model: p.cfg.Model,
+	}
+	if p.cfg.Temperature != nil {
+		params.Temperature = openai.Float(*p.cfg.Temperature)
+	}
+	if p.cfg.MaxTokens > 0 {
+		params.MaxCompletionTokens = openai.Int(int64(p.cfg.MaxTokens))
+	}
+	return params
+}
+
+func (p *openAIProvider) Generate(ctx context.Context, system, user string) (string, error) {
+	chatCompletion, err := p.client.Chat.Completions.New(ctx, p.params(system, user))
+	if err != nil {
+		return "", fmt.Errorf("openai: %w", err)
+	}
+	if len(chatCompletion.Choices) == 0 {
+		return "", fmt.Errorf("openai: empty response")
+	}
+	recordUsage(ctx, tokenUsage{
+		Model:        chatCompletion.Model,
+		InputTokens:  int(chatCompletion.Usage.PromptTokens),
+		OutputTokens: int(chatCompletion.Usage.CompletionTokens),
+	})
+	return strings.TrimSpace(chatCompletion.Choices[0].Message.Content), nil
+}
+
+func (p *openAIProvider) GenerateStream(ctx context.Context, system, user string, w io.Writer) (string, error) {
+	params := p.params(system, user)
+	// 最后一个分段会带上整个请求的用量，它的 choices 为空
+	params.StreamOptions.IncludeUsage = openai.Bool(true)
+	stream := p.client.Chat.Completions.NewStreaming(ctx, params)
+	defer stream.Close()
+	var content strings.Builder
+	for stream.Next() {
+		chunk := stream.Current()
+		if chunk.Usage.TotalTokens > 0 {
+			recordUsage(ctx, tokenUsage{
+				Model:        chunk.Model,
+				InputTokens:  int(chunk.Usage.PromptTokens),
+				OutputTokens: int(chunk.Usage.CompletionTokens),
+			})
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		delta := chunk.Choices[0].Delta.Content
+		content.WriteString(delta)
+		io.WriteString(w, delta)
+	}
+	if err := stream.Err(); err != nil {
+		return "", fmt.Errorf("openai: %w", err)
+	}
+	return strings.TrimSpace(content.String()), nil
+}
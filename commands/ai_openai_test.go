@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAIProviderBaseURLKey(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-official-key")
+	t.Setenv("OPENAI_ORG_ID", "org-official")
+	t.Setenv("VLLM_API_KEY", "vllm-key")
+	tests := []struct {
+		name      string
+		apiKeyEnv string
+		want      string
+	}{
+		{"without api_key_env", "", ""},
+		{"with api_key_env", "VLLM_API_KEY", "Bearer vllm-key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header http.Header
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Clone()
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"id":"1","object":"chat.completion","model":"qwen","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"feat: x"}}]}`))
+			}))
+			defer srv.Close()
+			p, err := newOpenAIProvider(ProviderConfig{BaseURL: srv.URL, APIKeyEnv: tt.apiKeyEnv, Model: "qwen"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := p.Generate(context.Background(), "system", "diff"); err != nil {
+				t.Fatal(err)
+			}
+			if got := header.Get("Authorization"); got != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+			if got := header.Get("OpenAI-Organization"); got != "" {
+				t.Errorf("OpenAI-Organization = %q, want empty", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const (
+	defaultLanguage = "zh"
+	promptExt       = ".prompt"
+)
+
+// languageNames 将常用的语言代码转换为提示词中使用的语言名称，其余取值原样使用
+var languageNames = map[string]string{
+	"zh":    "简体中文",
+	"zh-cn": "简体中文",
+	"zh-tw": "繁體中文",
+	"en":    "English",
+	"ja":    "日本語",
+	"ko":    "한국어",
+	"de":    "Deutsch",
+	"fr":    "Français",
+	"es":    "Español",
+}
+
+// promptData 是提示词模板中可以使用的变量
+type promptData struct {
+	// Language 是提交信息使用的语言，例如 "English"
+	Language string
+	// Branch 是当前分支名
+	Branch string
+	// Version 是分支名或目录名中 regexpProject 匹配到的版本，例如 feat-3.4.0
+	Version string
+	// Ticket 是分支名中的需求编号，例如 PROJ-123
+	Ticket string
+	// RecentCommits 是最近的提交标题
+	RecentCommits []string
+	// Files 是暂存区中改动的文件
+	Files []string
+	// Scope 是根据 Files 推断出的 scope，为空表示无法确定
+	Scope string
+	// Types 是允许的提交类型，即 ai.types 或默认列表，模板中使用 {{join .Types "|"}}
+	Types []string
+}
+
+// promptTemplate 是一个提示词模板，Source 为 builtin 或模板文件路径
+type promptTemplate struct {
+	Name   string
+	Source string
+	Text   string
+}
+
+// languageName 返回语言代码对应的名称
+func languageName(lang string) string {
+	if name, ok := languageNames[strings.ToLower(strings.TrimSpace(lang))]; ok {
+		return name
+	}
+	return strings.TrimSpace(lang)
+}
+
+// renderPrompt 使用 text/template 渲染提示词
+func renderPrompt(name, text string, data promptData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// promptDirs 返回模板目录，后面的目录优先级更高：~/.gitx/prompts/，<repo>/.gitx/prompts/
+func promptDirs() []string {
+	dirs := []string{path.Join(path.Dir(getConfigFilePath()), "prompts")}
+	if root, err := runCommand("git", "rev-parse", "--show-toplevel"); err == nil && root != "" {
+		dirs = append(dirs, path.Join(root, ".gitx", "prompts"))
+	}
+	return dirs
+}
+
+// loadPromptTemplates 加载内置模板以及模板目录中的 *.prompt，同名时仓库内的模板覆盖全局模板，全局模板覆盖内置模板
+func loadPromptTemplates() map[string]promptTemplate {
+	templates := map[string]promptTemplate{
+		"default": {Name: "default", Source: "builtin", Text: defaultPrompt},
+		"github":  {Name: "github", Source: "builtin", Text: githubPrompt},
+	}
+	for _, dir := range promptDirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				warningLog("failed to read prompt directory %s: %v", dir, err)
+			}
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), promptExt) {
+				continue
+			}
+			file := path.Join(dir, entry.Name())
+			data, err := os.ReadFile(file)
+			if err != nil {
+				warningLog("failed to read prompt %s: %v", file, err)
+				continue
+			}
+			name := strings.TrimSuffix(entry.Name(), promptExt)
+			templates[name] = promptTemplate{Name: name, Source: file, Text: string(data)}
+		}
+	}
+	return templates
+}
+
+// promptNames 返回所有可用模板的名称
+func promptNames(templates map[string]promptTemplate) []string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// findPrompt 按名称查找模板
+func findPrompt(name string) (promptTemplate, error) {
+	templates := loadPromptTemplates()
+	tmpl, ok := templates[name]
+	if !ok {
+		return promptTemplate{}, fmt.Errorf("prompt [%s] not found (available: %s)", name, strings.Join(promptNames(templates), "|"))
+	}
+	return tmpl, nil
+}
+
+// collectPromptData 从当前仓库中收集模板变量
+func collectPromptData(language string) promptData {
+	data := promptData{Language: language, Types: commitTypes()}
+	if branch, err := runCommand("git", "rev-parse", "--abbrev-ref", "HEAD"); err == nil {
+		data.Branch = branch
+	}
+	data.Version = projectVersion(data.Branch)
+	if ids, err := ticketIDs(data.Branch); err == nil && len(ids) > 0 {
+		data.Ticket = ids[0]
+	}
+	if subjects, err := runCommand("git", "log", "-n", "10", "--format=%s"); err == nil && subjects != "" {
+		data.RecentCommits = strings.Split(subjects, "\n")
+	}
+	if files, err := runCommand("git", stagedDiffArgs("--name-only", "-z")...); err == nil {
+		data.Files = splitNull(files)
+	}
+	data.Scope = inferScope(data.Files, config.AM.Scopes)
+	return data
+}
+
+// projectVersion 从当前目录名中取出版本，例如 feat-3.4.0，取不到时再尝试分支名
+func projectVersion(branch string) string {
+	if pwd, err := os.Getwd(); err == nil {
+		if matches := regexpProject.FindStringSubmatch(path.Base(pwd)); matches != nil {
+			return matches[1]
+		}
+	}
+	if matches := regexpProject.FindStringSubmatch(branch); matches != nil {
+		return matches[1]
+	}
+	return ""
+}
@@ -0,0 +1,22 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderPromptTypes(t *testing.T) {
+	data := promptData{Language: "English", Types: []string{"feat", "fix", "ops"}}
+	for name, text := range map[string]string{"default": defaultPrompt, "github": githubPrompt} {
+		out, err := renderPrompt(name, text, data)
+		if err != nil {
+			t.Fatalf("renderPrompt(%s) error: %v", name, err)
+		}
+		if !strings.Contains(out, "feat|fix|ops") && !strings.Contains(out, "feat, fix, ops") {
+			t.Errorf("renderPrompt(%s) does not list the configured types", name)
+		}
+		if strings.Contains(out, "(feat|test|revert|chore|style|refactor|fix)") {
+			t.Errorf("renderPrompt(%s) still hard-codes the type list", name)
+		}
+	}
+}
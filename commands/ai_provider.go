@@ -0,0 +1,200 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Provider 是 AI 后端的统一接口，system 为系统提示词，user 为用户消息
+type Provider interface {
+	Generate(ctx context.Context, system, user string) (string, error)
+}
+
+// StreamProvider 是支持流式输出的后端，每收到一段内容就写入 w，返回完整的内容
+type StreamProvider interface {
+	Provider
+	GenerateStream(ctx context.Context, system, user string, w io.Writer) (string, error)
+}
+
+// ProviderConfig 是创建 Provider 时使用的配置，对应 ~/.gitx/config.json 中的 ai.providers.<name>
+type ProviderConfig struct {
+	// Type 指定使用哪个已注册的后端，为空时与配置名相同，例如 vllm 可以设置为 openai
+	Type        string   `json:"type,omitempty"`
+	BaseURL     string   `json:"base_url,omitempty"`
+	Model       string   `json:"model,omitempty"`
+	APIKeyEnv   string   `json:"api_key_env,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	// InputPrice 和 OutputPrice 是每百万 token 的美元价格，用于估算费用
+	InputPrice  float64 `json:"input_price,omitempty"`
+	OutputPrice float64 `json:"output_price,omitempty"`
+}
+
+// apiKey 读取 APIKeyEnv 指定的环境变量，未设置时使用 defaultEnv。
+// 设置了 BaseURL 时只读取 APIKeyEnv，避免把官方的 key 发送给第三方服务
+func (c ProviderConfig) apiKey(defaultEnv string) (string, string) {
+	env := c.APIKeyEnv
+	if env == "" && c.BaseURL == "" {
+		env = defaultEnv
+	}
+	if env == "" {
+		return "", ""
+	}
+	return os.Getenv(env), env
+}
+
+// ProviderFactory 根据配置创建一个 Provider
+type ProviderFactory func(cfg ProviderConfig) (Provider, error)
+
+var providerFactories = map[string]ProviderFactory{}
+
+// RegisterProvider 注册一个 AI 后端，同名的后端会被覆盖
+func RegisterProvider(name string, factory ProviderFactory) {
+	providerFactories[name] = factory
+}
+
+func newProvider(name string, cfg ProviderConfig) (Provider, error) {
+	kind := cfg.Type
+	if kind == "" {
+		kind = name
+	}
+	factory, ok := providerFactories[kind]
+	if !ok {
+		return nil, fmt.Errorf("unsupported AI agent: %s (available: %s)", kind, strings.Join(providerNames(), "|"))
+	}
+	return factory(cfg)
+}
+
+// providerConfig 返回配置文件中 name 对应的配置，不存在时返回空配置
+func providerConfig(name string) ProviderConfig {
+	return config.AI.Providers[name]
+}
+
+func providerNames() []string {
+	names := make([]string, 0, len(providerFactories))
+	for name := range providerFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// providerChain 返回依次尝试的后端列表，--agent 显式指定时排在最前面，其余按 ai.fallback 顺序补充
+func providerChain(primary string, pinned bool) []string {
+	var chain []string
+	if pinned || len(config.AI.Fallback) == 0 {
+		chain = append(chain, primary)
+	}
+	for _, name := range config.AI.Fallback {
+		if !slices.Contains(chain, name) {
+			chain = append(chain, name)
+		}
+	}
+	return chain
+}
+
+// aiGenerator 按回退链调用后端，命令行参数只作用于链中的第一个后端
+type aiGenerator struct {
+	chain   []string
+	resolve func(name string) ProviderConfig
+	timeout time.Duration
+	// stream 不为空时，支持流式输出的后端会把内容实时写入 stream
+	stream io.Writer
+	// cache 为空时不使用缓存，refresh 为 true 时跳过读取缓存但仍然写入新的响应
+	cache   *responseCache
+	refresh bool
+	// repo 是写入用量日志的仓库名
+	repo string
+	// confirmed 为 true 时本次运行已经提示过 token 数和密钥，--split 的每一组不再重复提示
+	confirmed bool
+}
+
+// available 返回回退链中第一个可以创建的后端，例如设置了 API key 的后端，也就是实际会被调用的后端
+func (g *aiGenerator) available() (string, ProviderConfig) {
+	for _, name := range g.chain {
+		cfg := g.resolve(name)
+		if _, err := newProvider(name, cfg); err == nil {
+			return name, cfg
+		}
+	}
+	return g.chain[0], g.resolve(g.chain[0])
+}
+
+// generate 按顺序调用回退链中的后端，出错、超时或 validate 不通过时尝试下一个，
+// 返回第一个有效的结果以及产生该结果的后端名称
+func (g *aiGenerator) generate(ctx context.Context, system, user string, validate func(msg string) error) (string, string, error) {
+	var errs []error
+	for _, name := range g.chain {
+		cfg := g.resolve(name)
+		var key string
+		if g.cache != nil {
+			key = cacheKey(name, cfg.Model, system, user)
+			if msg, ok := g.cache.get(key); ok && !g.refresh && (validate == nil || validate(msg) == nil) {
+				successLog("Using cached response from [%s], pass --no-cache to call the API again", name)
+				if g.stream != nil {
+					io.WriteString(g.stream, msg+"\n")
+				}
+				return msg, name, nil
+			}
+		}
+		usageCtx, usage := withUsage(ctx)
+		msg, err := generateOnce(usageCtx, name, cfg, g.timeout, system, user, g.stream)
+		if err == nil {
+			logUsage(g.repo, name, cfg, *usage, system, user, msg)
+		}
+		if err == nil && validate != nil {
+			err = validate(msg)
+		}
+		if err == nil {
+			if g.cache != nil {
+				g.cache.put(key, cacheEntry{Provider: name, Model: cfg.Model, CreatedAt: time.Now(), Response: msg})
+			}
+			return msg, name, nil
+		}
+		if ctx.Err() != nil {
+			return "", name, err
+		}
+		errs = append(errs, fmt.Errorf("[%s] %w", name, err))
+		if len(g.chain) > 1 {
+			warningLog("AI agent [%s] failed: %v", name, err)
+		}
+	}
+	return "", "", errors.Join(errs...)
+}
+
+func generateOnce(ctx context.Context, name string, cfg ProviderConfig, timeout time.Duration, system, user string, stream io.Writer) (string, error) {
+	provider, err := newProvider(name, cfg)
+	if err != nil {
+		return "", err
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	var msg string
+	if sp, ok := provider.(StreamProvider); ok && stream != nil {
+		msg, err = sp.GenerateStream(ctx, system, user, stream)
+		io.WriteString(stream, "\n")
+	} else {
+		msg, err = provider.Generate(ctx, system, user)
+	}
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("timed out after %s: %w", timeout, err)
+		}
+		return "", err
+	}
+	msg = strings.TrimSpace(msg)
+	if msg == "" {
+		return "", fmt.Errorf("empty response")
+	}
+	return msg, nil
+}
@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// gitx am 提交后的行为，对应 am.after_commit
+const (
+	afterCommitNone           = "none"
+	afterCommitPush           = "push"
+	afterCommitPullPush       = "pull-push"
+	afterCommitPullRebasePush = "pull-rebase-push"
+)
+
+var afterCommitModes = []string{afterCommitNone, afterCommitPush, afterCommitPullPush, afterCommitPullRebasePush}
+
+// pullConflictError 表示 git pull 产生了冲突，Files 为冲突的文件
+type pullConflictError struct {
+	Rebase bool
+	Files  []string
+	Err    error
+}
+
+func (e *pullConflictError) Error() string {
+	return fmt.Sprintf("pull stopped with conflicts in %d file(s): %v", len(e.Files), e.Err)
+}
+
+func (e *pullConflictError) Unwrap() error {
+	return e.Err
+}
+
+// report 输出冲突的文件以及后续的处理步骤
+func (e *pullConflictError) report() {
+	warningLog("git pull stopped with conflicts, your commit is kept locally and nothing was pushed.")
+	for _, file := range e.Files {
+		warningLog("  conflict: %s", file)
+	}
+	if e.Rebase {
+		warningLog("Resolve the conflicts, `git add` the files and run `git rebase --continue`, or `git rebase --abort` to undo the pull. Then run `git push`.")
+	} else {
+		warningLog("Resolve the conflicts, `git add` the files and run `git commit`, or `git merge --abort` to undo the pull. Then run `git push`.")
+	}
+}
+
+// validateAfterCommitMode 检查 am.after_commit 是否为允许的取值
+func validateAfterCommitMode(mode string) error {
+	if !slices.Contains(afterCommitModes, mode) {
+		return fmt.Errorf("invalid am.after_commit: %s (available: %s)", mode, strings.Join(afterCommitModes, "|"))
+	}
+	return nil
+}
+
+// pushAfterCommit 按 mode 执行提交后的拉取和推送，远程分支不存在时直接推送并设置上游分支
+func pushAfterCommit(mode string) error {
+	if err := validateAfterCommitMode(mode); err != nil {
+		return err
+	}
+	if mode == afterCommitNone {
+		return nil
+	}
+	cur, err := runCommand("git", "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return err
+	}
+	if !remoteBranchExists(".", cur) {
+		return runGit("push", "--set-upstream", "origin", cur)
+	}
+	if mode == afterCommitPullPush || mode == afterCommitPullRebasePush {
+		rebase := mode == afterCommitPullRebasePush
+		args := []string{"pull", "--no-edit"}
+		if rebase {
+			args = []string{"pull", "--rebase"}
+		}
+		if err := runGit(args...); err != nil {
+			if files := conflictedFiles(); len(files) > 0 {
+				return &pullConflictError{Rebase: rebase, Files: files, Err: err}
+			}
+			return err
+		}
+	}
+	return runGit("push")
+}
+
+// runGit 执行 git 命令并输出结果，保留远程返回的提示，例如创建合并请求的链接，出错时由调用方处理
+func runGit(args ...string) error {
+	cmd := exec.Command("git", args...)
+	data, err := cmd.CombinedOutput()
+	if isDebug {
+		log.Println(cmd.String())
+	}
+	if output := strings.TrimSpace(string(data)); output != "" {
+		log.Println(output)
+	}
+	if err != nil {
+		return fmt.Errorf("%s [%s]", err.Error(), cmd.String())
+	}
+	return nil
+}
+
+// conflictedFiles 返回未解决冲突的文件
+func conflictedFiles() []string {
+	output, err := runCommand("git", "diff", "--name-only", "-z", "--diff-filter=U")
+	if err != nil {
+		return nil
+	}
+	return splitNull(output)
+}
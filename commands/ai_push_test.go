@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestPushAfterCommitInvalidMode(t *testing.T) {
+	// 不在仓库中，任何 git 命令都会失败，取值错误时必须在执行 git 之前返回
+	t.Chdir(t.TempDir())
+	for _, mode := range []string{"force", ""} {
+		err := pushAfterCommit(mode)
+		if err == nil || !strings.Contains(err.Error(), "invalid am.after_commit") {
+			t.Errorf("pushAfterCommit(%q) error = %v", mode, err)
+		}
+	}
+	if err := pushAfterCommit(afterCommitNone); err != nil {
+		t.Errorf("pushAfterCommit(none) error = %v", err)
+	}
+}
+
+func TestPushAfterCommitLogsOutput(t *testing.T) {
+	remote, work := t.TempDir(), t.TempDir()
+	t.Setenv("GIT_AUTHOR_NAME", "gitx")
+	t.Setenv("GIT_AUTHOR_EMAIL", "gitx@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "gitx")
+	t.Setenv("GIT_COMMITTER_EMAIL", "gitx@example.com")
+	git := func(dir string, args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git(remote, "init", "-q", "--bare")
+	git(work, "init", "-q", "-b", "feat-1.0")
+	git(work, "remote", "add", "origin", remote)
+	git(work, "commit", "-q", "--allow-empty", "-m", "feat: first")
+	t.Chdir(work)
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	// 远程分支不存在时直接推送并设置上游分支，git 的输出需要保留
+	if err := pushAfterCommit(afterCommitPullPush); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "feat-1.0 -> feat-1.0") {
+		t.Errorf("push output not logged: %q", buf.String())
+	}
+	git(work, "commit", "-q", "--allow-empty", "-m", "fix: second")
+	buf.Reset()
+	if err := pushAfterCommit(afterCommitPullPush); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "feat-1.0 -> feat-1.0") {
+		t.Errorf("push output not logged: %q", buf.String())
+	}
+}
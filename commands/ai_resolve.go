@@ -0,0 +1,314 @@
+package commands
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+//go:embed prompts/resolve.prompt
+var resolvePrompt string
+
+const (
+	resolutionMarker    = "=== RESOLUTION ==="
+	conflictContextSize = 5
+
+	hunkActionAccept = "Accept"
+	hunkActionReject = "Reject"
+	hunkActionStop   = "Stop, resolve the rest manually"
+)
+
+var aiResolve bool
+
+func init() {
+	for _, cmd := range []*cobra.Command{FetchCmd, SyncCmd, MergeBackCmd} {
+		cmd.Flags().BoolVarP(&aiResolve, "ai-resolve", "", false, "Ask AI to propose a resolution for each conflicted hunk when the merge conflicts")
+	}
+}
+
+// conflictHunk 是一个冲突块，Start 和 End 是冲突标记所在的行，包含在块内
+type conflictHunk struct {
+	Start, End int
+	Ours       []string
+	Base       []string
+	Theirs     []string
+}
+
+// mergeBranch 执行 git merge，出现冲突且开启 --ai-resolve 时逐个冲突块提出解决方案，
+// 全部解决并确认后完成合并，否则保留合并状态并退出
+func mergeBranch(cmd *cobra.Command, args ...string) {
+	args = append([]string{"merge"}, args...)
+	if !aiResolve {
+		execCommand("git", args...)
+		return
+	}
+	output, err := runCommand("git", args...)
+	log.Println(output)
+	if err == nil {
+		return
+	}
+	files := conflictedFiles()
+	if len(files) == 0 {
+		errLog("git merge fail: %v", err)
+	}
+	warningLog("git merge stopped with conflicts in %d files, asking AI for resolutions.", len(files))
+	if err := resolveConflicts(newAIGenerator(cmd), languageName(commitLanguage(cmd)), files); err != nil {
+		errLog("%v", err)
+	}
+	if remaining := conflictedFiles(); len(remaining) > 0 {
+		for _, file := range remaining {
+			warningLog("  conflict: %s", file)
+		}
+		errLog("Resolve the remaining conflicts, `git add` the files and run `git commit`, or `git merge --abort` to undo the merge.")
+	}
+	confirm := promptui.Prompt{Label: "All conflicts are resolved and staged, commit the merge", IsConfirm: true}
+	if _, err := confirm.Run(); err != nil {
+		errLog("Merge not committed, review the staged changes and run `git commit`, or `git merge --abort` to undo the merge.")
+	}
+	if output, err := runCommand("git", "commit", "--no-edit"); err != nil {
+		errLog("git commit fail: %v", err)
+	} else {
+		log.Println(output)
+	}
+}
+
+// resolveConflicts 逐个文件、逐个冲突块请求 AI 给出解决方案，每个冲突块都需要确认，
+// 一个文件中的冲突块全部接受后才会 git add 该文件
+func resolveConflicts(gen *aiGenerator, language string, files []string) error {
+	root, err := runCommand("git", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		lines, hunks, err := mergeConflictFile(root, file)
+		if err != nil {
+			warningLog("Skip %s: %v", file, err)
+			continue
+		}
+		accepted := 0
+		resolutions := make(map[int][]string, len(hunks))
+		stop := false
+		for i, hunk := range hunks {
+			successLog("%s: conflict %d/%d", file, i+1, len(hunks))
+			explanation, resolution, err := proposeResolution(gen, language, file, lines, hunk)
+			if err != nil {
+				warningLog("Propose resolution fail: %v", err)
+				continue
+			}
+			log.Println(explanation)
+			fmt.Println(resolutionPatch(file, lines, hunk, resolution))
+			menu := promptui.Select{
+				Label: "Apply this resolution?",
+				Items: []string{hunkActionAccept, hunkActionReject, hunkActionStop},
+			}
+			_, action, err := menu.Run()
+			if err != nil || action == hunkActionStop {
+				stop = true
+				break
+			}
+			if action == hunkActionAccept {
+				resolutions[i] = resolution
+				accepted++
+			}
+		}
+		if accepted > 0 {
+			if err := writeResolvedFile(filepath.Join(root, file), applyResolutions(lines, hunks, resolutions)); err != nil {
+				return err
+			}
+		}
+		if accepted == len(hunks) {
+			if _, err := runCommand("git", "-C", root, "add", "--", file); err != nil {
+				return err
+			}
+			successLog("Resolved and staged %s", file)
+		} else if accepted > 0 {
+			warningLog("%s: %d of %d conflicts resolved, the rest are left with conflict markers", file, accepted, len(hunks))
+		}
+		if stop {
+			break
+		}
+	}
+	return nil
+}
+
+// writeResolvedFile 写回解决后的文件，保留原文件的权限，例如脚本的可执行位
+func writeResolvedFile(path, content string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chmod(path, info.Mode().Perm())
+}
+
+// mergeConflictFile 用 git checkout-index --stage 取出 base、ours 和 theirs，
+// 再用 git merge-file --diff3 生成带有 base 的冲突块，不受 merge.conflictStyle 影响
+func mergeConflictFile(root, file string) ([]string, []conflictHunk, error) {
+	output, err := runCommand("git", "-C", root, "checkout-index", "--stage=all", "--temp", "--", file)
+	if err != nil {
+		return nil, nil, err
+	}
+	stages, _, _ := strings.Cut(output, "\t")
+	temps := strings.Fields(stages)
+	for _, temp := range temps {
+		if temp != "." {
+			defer os.Remove(filepath.Join(root, temp))
+		}
+	}
+	if len(temps) != 3 || slices.Contains(temps, ".") {
+		return nil, nil, errors.New("the file was added or deleted on one side, resolve it manually")
+	}
+	base, ours, theirs := filepath.Join(root, temps[0]), filepath.Join(root, temps[1]), filepath.Join(root, temps[2])
+	merged, err := exec.Command("git", "merge-file", "-p", "--diff3", "-L", "ours", "-L", "base", "-L", "theirs", ours, base, theirs).Output()
+	// merge-file 的退出码是冲突的数量，只有负数（大于 127）表示出错
+	var exitErr *exec.ExitError
+	if err != nil && (!errors.As(err, &exitErr) || exitErr.ExitCode() > 127) {
+		return nil, nil, fmt.Errorf("git merge-file fail: %w", err)
+	}
+	lines := strings.Split(string(merged), "\n")
+	hunks := parseConflictHunks(lines)
+	if len(hunks) == 0 {
+		return nil, nil, errors.New("no conflict markers found")
+	}
+	return lines, hunks, nil
+}
+
+// parseConflictHunks 解析 diff3 格式的冲突标记
+func parseConflictHunks(lines []string) []conflictHunk {
+	var hunks []conflictHunk
+	var cur *conflictHunk
+	section := ""
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "<<<<<<< ") || line == "<<<<<<<":
+			cur = &conflictHunk{Start: i}
+			section = "ours"
+		case cur == nil:
+		case strings.HasPrefix(line, "||||||| ") || line == "|||||||":
+			section = "base"
+		case line == "=======":
+			section = "theirs"
+		case strings.HasPrefix(line, ">>>>>>> ") || line == ">>>>>>>":
+			cur.End = i
+			hunks = append(hunks, *cur)
+			cur = nil
+		case section == "ours":
+			cur.Ours = append(cur.Ours, line)
+		case section == "base":
+			cur.Base = append(cur.Base, line)
+		case section == "theirs":
+			cur.Theirs = append(cur.Theirs, line)
+		}
+	}
+	return hunks
+}
+
+// proposeResolution 请求 AI 为一个冲突块给出解决方案，返回说明和替换冲突块的代码
+func proposeResolution(gen *aiGenerator, language, file string, lines []string, hunk conflictHunk) (string, []string, error) {
+	before := lines[max(0, hunk.Start-conflictContextSize):hunk.Start]
+	after := lines[hunk.End+1 : min(len(lines), hunk.End+1+conflictContextSize)]
+	user := fmt.Sprintf("文件：%s\n\n冲突前的代码：\n%s\n\n当前分支（ours）：\n%s\n\n共同祖先（base）：\n%s\n\n合并进来的分支（theirs）：\n%s\n\n冲突后的代码：\n%s",
+		file, strings.Join(before, "\n"), strings.Join(hunk.Ours, "\n"), strings.Join(hunk.Base, "\n"),
+		strings.Join(hunk.Theirs, "\n"), strings.Join(after, "\n"))
+	user, err := redactSecrets(file, user)
+	if err != nil {
+		return "", nil, err
+	}
+	sp, err := renderPrompt("builtin/resolve", resolvePrompt, promptData{Language: language})
+	if err != nil {
+		return "", nil, err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	msg, _, err := gen.generate(ctx, sp, user, func(msg string) error {
+		_, _, err := parseResolution(msg)
+		return err
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return parseResolution(msg)
+}
+
+// parseResolution 拆分说明和解决后的代码，代码中不能包含冲突标记或者被替换的密钥
+func parseResolution(msg string) (string, []string, error) {
+	explanation, code, ok := strings.Cut(msg, resolutionMarker)
+	if !ok {
+		return "", nil, fmt.Errorf("missing %q in the response", resolutionMarker)
+	}
+	var resolution []string
+	for _, line := range strings.Split(strings.Trim(code, "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		for _, marker := range []string{"<<<<<<<", "|||||||", ">>>>>>>"} {
+			if strings.HasPrefix(line, marker) {
+				return "", nil, errors.New("resolution still contains conflict markers")
+			}
+		}
+		if strings.Contains(line, "[REDACTED:") {
+			return "", nil, errors.New("resolution contains redacted secrets, resolve it manually")
+		}
+		resolution = append(resolution, line)
+	}
+	return strings.TrimSpace(explanation), resolution, nil
+}
+
+// resolutionPatch 以 unified diff 的形式展示冲突块被替换后的效果
+func resolutionPatch(file string, lines []string, hunk conflictHunk, resolution []string) string {
+	start := max(0, hunk.Start-conflictContextSize)
+	end := min(len(lines), hunk.End+1+conflictContextSize)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s (conflict)\n+++ b/%s (proposed)\n", file, file)
+	oldLen := end - start
+	newLen := oldLen - (hunk.End - hunk.Start + 1) + len(resolution)
+	fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", start+1, oldLen, start+1, newLen)
+	for _, line := range lines[start:hunk.Start] {
+		sb.WriteString(" " + line + "\n")
+	}
+	for _, line := range lines[hunk.Start : hunk.End+1] {
+		sb.WriteString("-" + line + "\n")
+	}
+	for _, line := range resolution {
+		sb.WriteString("+" + line + "\n")
+	}
+	for _, line := range lines[hunk.End+1 : end] {
+		sb.WriteString(" " + line + "\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// applyResolutions 用接受的方案替换冲突块，未接受的冲突块去掉 base 部分，
+// 写回与 git merge 默认格式相同的冲突标记
+func applyResolutions(lines []string, hunks []conflictHunk, resolutions map[int][]string) string {
+	var out []string
+	next := 0
+	for i, hunk := range hunks {
+		out = append(out, lines[next:hunk.Start]...)
+		if resolution, ok := resolutions[i]; ok {
+			out = append(out, resolution...)
+		} else {
+			out = append(out, lines[hunk.Start])
+			out = append(out, hunk.Ours...)
+			out = append(out, "=======")
+			out = append(out, hunk.Theirs...)
+			out = append(out, lines[hunk.End])
+		}
+		next = hunk.End + 1
+	}
+	out = append(out, lines[next:]...)
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sampleConflict 是 git merge-file --diff3 输出的两个冲突块
+const sampleConflict = `package main
+
+<<<<<<< ours
+const port = 8080
+||||||| base
+const port = 80
+=======
+const port = 9090
+>>>>>>> theirs
+
+func main() {
+<<<<<<< ours
+	serve(port)
+|||||||
+	listen(port)
+=======
+	listen(port, tls)
+>>>>>>>
+}
+`
+
+func TestParseConflictHunks(t *testing.T) {
+	hunks := parseConflictHunks(strings.Split(sampleConflict, "\n"))
+	want := []conflictHunk{
+		{Start: 2, End: 8, Ours: []string{"const port = 8080"}, Base: []string{"const port = 80"}, Theirs: []string{"const port = 9090"}},
+		{Start: 11, End: 17, Ours: []string{"\tserve(port)"}, Base: []string{"\tlisten(port)"}, Theirs: []string{"\tlisten(port, tls)"}},
+	}
+	if !reflect.DeepEqual(hunks, want) {
+		t.Errorf("hunks = %+v, want %+v", hunks, want)
+	}
+	// 没有开始标记的分隔行不是冲突
+	if hunks := parseConflictHunks([]string{"a", "=======", "b", ">>>>>>> theirs"}); len(hunks) != 0 {
+		t.Errorf("hunks without start marker = %+v", hunks)
+	}
+	// 没有结束标记的冲突块被丢弃
+	if hunks := parseConflictHunks([]string{"<<<<<<< ours", "a", "=======", "b"}); len(hunks) != 0 {
+		t.Errorf("unterminated hunks = %+v", hunks)
+	}
+}
+
+func TestApplyResolutions(t *testing.T) {
+	lines := strings.Split(sampleConflict, "\n")
+	hunks := parseConflictHunks(lines)
+	tests := []struct {
+		name        string
+		resolutions map[int][]string
+		want        string
+	}{
+		{
+			name:        "all accepted",
+			resolutions: map[int][]string{0: {"const port = 9090"}, 1: {"\tserve(port, tls)"}},
+			want:        "package main\n\nconst port = 9090\n\nfunc main() {\n\tserve(port, tls)\n}\n",
+		},
+		{
+			name:        "resolved to nothing",
+			resolutions: map[int][]string{0: nil, 1: {"\tserve(port, tls)"}},
+			want:        "package main\n\n\nfunc main() {\n\tserve(port, tls)\n}\n",
+		},
+		{
+			name:        "rejected hunk as merge markers",
+			resolutions: map[int][]string{1: {"\tserve(port, tls)"}},
+			want:        "package main\n\n<<<<<<< ours\nconst port = 8080\n=======\nconst port = 9090\n>>>>>>> theirs\n\nfunc main() {\n\tserve(port, tls)\n}\n",
+		},
+		{
+			name:        "none accepted",
+			resolutions: map[int][]string{},
+			want: "package main\n\n<<<<<<< ours\nconst port = 8080\n=======\nconst port = 9090\n>>>>>>> theirs\n\n" +
+				"func main() {\n<<<<<<< ours\n\tserve(port)\n=======\n\tlisten(port, tls)\n>>>>>>>\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applyResolutions(lines, hunks, tt.resolutions); got != tt.want {
+				t.Errorf("applyResolutions = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseResolution(t *testing.T) {
+	explanation, code, err := parseResolution("Keep the new port from theirs.\n\n" + resolutionMarker + "\n```go\nconst port = 9090\n```\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if explanation != "Keep the new port from theirs." || !reflect.DeepEqual(code, []string{"const port = 9090"}) {
+		t.Errorf("parseResolution = %q, %q", explanation, code)
+	}
+	for msg, want := range map[string]string{
+		"const port = 9090": "missing",
+		resolutionMarker + "\n<<<<<<< ours\nconst port = 9090":      "conflict markers",
+		resolutionMarker + "\ntoken := \"[REDACTED:github-token]\"": "redacted secrets",
+	} {
+		if _, _, err := parseResolution(msg); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseResolution(%q) error = %v, want %q", msg, err, want)
+		}
+	}
+}
+
+func TestWriteResolvedFileKeepsMode(t *testing.T) {
+	file := t.TempDir() + "/deploy.sh"
+	if err := os.WriteFile(file, []byte("<<<<<<< ours\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeResolvedFile(file, "#!/bin/sh\n"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+	}
+	if data, _ := os.ReadFile(file); string(data) != "#!/bin/sh\n" {
+		t.Errorf("content = %q", data)
+	}
+}
@@ -0,0 +1,285 @@
+package commands
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+)
+
+//go:embed prompts/split.prompt
+var splitPrompt string
+
+const (
+	planActionAccept     = "Accept"
+	planActionRegenerate = "Regenerate"
+	planActionEdit       = "Edit in $EDITOR"
+	planActionSingle     = "Commit everything at once"
+	planActionAbort      = "Abort"
+)
+
+var regexpSplitGroup = regexp.MustCompile(`^\[(\d+)\]\s*(.*)$`)
+
+// splitGroup 是拆分计划中的一个提交
+type splitGroup struct {
+	Title string
+	Files []string
+}
+
+// formatSplitPlan 按照 split.prompt 约定的格式输出拆分计划，也用于在编辑器中修改
+func formatSplitPlan(groups []splitGroup) string {
+	parts := make([]string, len(groups))
+	for i, g := range groups {
+		parts[i] = fmt.Sprintf("[%d] %s\n%s", i+1, g.Title, strings.Join(g.Files, "\n"))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// parseSplitPlan 解析拆分计划，每个暂存的文件必须且只能出现在一个提交中
+func parseSplitPlan(plan string, staged []string) ([]splitGroup, error) {
+	var groups []splitGroup
+	seen := map[string]bool{}
+	for _, line := range strings.Split(strings.ReplaceAll(plan, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "```") {
+			continue
+		}
+		if m := regexpSplitGroup.FindStringSubmatch(line); m != nil {
+			groups = append(groups, splitGroup{Title: strings.TrimSpace(m[2])})
+			continue
+		}
+		file := strings.Trim(strings.TrimPrefix(line, "- "), "`")
+		if len(groups) == 0 {
+			return nil, fmt.Errorf("file %s appears before the first commit", file)
+		}
+		if !slices.Contains(staged, file) {
+			return nil, fmt.Errorf("file %s is not staged", file)
+		}
+		if seen[file] {
+			return nil, fmt.Errorf("file %s appears in more than one commit", file)
+		}
+		seen[file] = true
+		groups[len(groups)-1].Files = append(groups[len(groups)-1].Files, file)
+	}
+	var missing []string
+	for _, file := range staged {
+		if !seen[file] {
+			missing = append(missing, file)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("files missing from the plan: %s", strings.Join(missing, ", "))
+	}
+	groups = slices.DeleteFunc(groups, func(g splitGroup) bool { return len(g.Files) == 0 })
+	if len(groups) == 0 {
+		return nil, errors.New("empty plan")
+	}
+	return groups, nil
+}
+
+// splitAndCommit 让 AI 把暂存区的改动按文件分组，确认计划后依次暂存每一组并生成提交信息，
+// 中途失败或放弃时，尚未提交的改动会重新放回暂存区
+func splitAndCommit(gen *aiGenerator, tmpl promptTemplate, promptName string, data promptData, tickets []string) error {
+	names, err := runCommand("git", stagedDiffArgs("--name-only", "-z", "--no-renames")...)
+	if err != nil {
+		return err
+	}
+	staged := splitNull(names)
+	diff, err := runCommand("git", stagedDiffArgs("--no-renames")...)
+	if err != nil {
+		return err
+	}
+	excluded, err := excludedStagedFiles(staged)
+	if err != nil {
+		return err
+	}
+	if len(staged) < 2 {
+		warningLog("Only one file is staged, nothing to split.")
+		return commitDiff(gen, tmpl, promptName, data, tickets, diff)
+	}
+
+	// 估算 token 时除了拆分计划，还要算上之后为每一组生成提交信息的请求
+	sp, err := renderPrompt(tmpl.Source, tmpl.Text, data)
+	if err != nil {
+		return fmt.Errorf("render prompt [%s] fail: %w", tmpl.Source, err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	userMessage, err := diffMessage(ctx, gen, diff, splitPrompt, sp)
+	stop()
+	if err != nil {
+		if ctx.Err() != nil {
+			return errors.New("canceled")
+		}
+		return fmt.Errorf("summarize diff fail: %w", err)
+	}
+	userMessage = "暂存的文件：\n" + strings.Join(staged, "\n") + "\n\n" + userMessage
+	validate := func(plan string) error {
+		_, err := parseSplitPlan(plan, staged)
+		return err
+	}
+	planCommits := func() ([]splitGroup, error) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		plan, agent, err := gen.generate(ctx, splitPrompt, userMessage, validate)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("canceled")
+			}
+			return nil, err
+		}
+		successLog("Split plan generated by [%s]", agent)
+		return parseSplitPlan(plan, staged)
+	}
+	groups, err := planCommits()
+	if err != nil {
+		return fmt.Errorf("generate split plan fail: %w", err)
+	}
+
+	for !aiConfirm {
+		log.Println(formatSplitPlan(groups))
+		items := []string{planActionAccept, planActionRegenerate, planActionEdit, planActionSingle, planActionAbort}
+		menu := promptui.Select{
+			Label: fmt.Sprintf("Create these %d commits?", len(groups)),
+			Items: items,
+			Size:  len(items),
+		}
+		_, action, err := menu.Run()
+		if err != nil || action == planActionAbort {
+			return errCommitAborted
+		}
+		if action == planActionAccept {
+			break
+		}
+		switch action {
+		case planActionRegenerate:
+			gen.refresh = true
+			regenerated, err := planCommits()
+			gen.refresh = false
+			if err != nil {
+				warningLog("Generate split plan fail: %v", err)
+				continue
+			}
+			groups = regenerated
+		case planActionEdit:
+			edited, err := editInEditor(formatSplitPlan(groups))
+			if err != nil {
+				warningLog("Edit split plan fail: %v", err)
+				continue
+			}
+			parsed, err := parseSplitPlan(edited, staged)
+			if err != nil {
+				warningLog("Edited split plan is invalid: %v", err)
+				continue
+			}
+			groups = parsed
+		case planActionSingle:
+			return commitDiff(gen, tmpl, promptName, data, tickets, diff)
+		}
+	}
+	if len(excluded) > 0 {
+		warningLog("Excluded files are committed with the last commit: %s", strings.Join(excluded, ", "))
+		groups[len(groups)-1].Files = append(groups[len(groups)-1].Files, excluded...)
+	}
+	return commitGroups(gen, tmpl, promptName, data, tickets, groups)
+}
+
+// excludedStagedFiles 返回已暂存但被排除的文件，它们不参与拆分，但仍然需要提交
+func excludedStagedFiles(staged []string) ([]string, error) {
+	if len(excludePathspecs()) == 0 {
+		return nil, nil
+	}
+	names, err := runCommand("git", "diff", "--cached", "--name-only", "-z", "--no-renames")
+	if err != nil {
+		return nil, err
+	}
+	var excluded []string
+	for _, file := range splitNull(names) {
+		if !slices.Contains(staged, file) {
+			excluded = append(excluded, file)
+		}
+	}
+	return excluded, nil
+}
+
+// commitGroups 先保存每一组的 patch 并清空暂存区，再依次用 git apply --cached 暂存并提交
+func commitGroups(gen *aiGenerator, tmpl promptTemplate, promptName string, data promptData, tickets []string, groups []splitGroup) error {
+	patches := make([]string, len(groups))
+	for i, g := range groups {
+		patch, err := stagedPatch(g.Files)
+		if err != nil {
+			return err
+		}
+		patches[i] = patch
+	}
+	if err := unstageAll(); err != nil {
+		return err
+	}
+	for i, g := range groups {
+		successLog("Commit %d/%d: %s", i+1, len(groups), g.Title)
+		if err := applyCachedPatch(patches[i]); err != nil {
+			return restageGroups(patches[i:], fmt.Errorf("stage commit %d/%d fail: %w", i+1, len(groups), err))
+		}
+		diff, err := runCommand("git", stagedDiffArgs()...)
+		if err != nil {
+			return restageGroups(patches[i+1:], err)
+		}
+		groupData := data
+		groupData.Files = g.Files
+		groupData.Scope = inferScope(g.Files, config.AM.Scopes)
+		if err := commitDiff(gen, tmpl, promptName, groupData, tickets, diff); err != nil {
+			return restageGroups(patches[i+1:], err)
+		}
+	}
+	return nil
+}
+
+// unstageAll 清空暂存区，仓库的第一次提交之前 HEAD 不存在，git reset 会失败，改用 git read-tree --empty
+func unstageAll() error {
+	args := []string{"reset", "-q"}
+	if _, err := runCommand("git", "rev-parse", "-q", "--verify", "HEAD"); err != nil {
+		args = []string{"read-tree", "--empty"}
+	}
+	_, err := runCommand("git", args...)
+	return err
+}
+
+// restageGroups 把尚未提交的 patch 重新放回暂存区，保证失败时暂存区与开始时一致
+func restageGroups(patches []string, err error) error {
+	for _, patch := range patches {
+		if applyErr := applyCachedPatch(patch); applyErr != nil {
+			return errors.Join(err, fmt.Errorf("restore staged changes fail: %w", applyErr))
+		}
+	}
+	if len(patches) > 0 {
+		warningLog("Changes not yet committed are staged again.")
+	}
+	return err
+}
+
+// stagedPatch 返回指定文件在暂存区中的 patch，保留原始输出以便 git apply 使用
+func stagedPatch(files []string) (string, error) {
+	args := append([]string{"--literal-pathspecs", "diff", "--cached", "--binary", "--no-renames", "--"}, files...)
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("git diff --cached fail: %w", err)
+	}
+	return string(out), nil
+}
+
+func applyCachedPatch(patch string) error {
+	cmd := exec.Command("git", "apply", "--cached", "--binary", "-")
+	cmd.Stdin = strings.NewReader(patch)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git apply --cached fail: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
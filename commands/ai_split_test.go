@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSplitPlan(t *testing.T) {
+	staged := []string{"commands/ai_split.go", "commands/prompts/split.prompt", "README.md", "README-cn.md"}
+	plan := "```\n" +
+		"[1] feat(ai): add am --split\n" +
+		"- commands/ai_split.go\n" +
+		"`commands/prompts/split.prompt`\n" +
+		"\n" +
+		"[2] docs: document am --split\r\n" +
+		"README.md\r\n" +
+		"README-cn.md\n" +
+		"[3] chore: nothing left\n" +
+		"```\n"
+	groups, err := parseSplitPlan(plan, staged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []splitGroup{
+		{Title: "feat(ai): add am --split", Files: []string{"commands/ai_split.go", "commands/prompts/split.prompt"}},
+		{Title: "docs: document am --split", Files: []string{"README.md", "README-cn.md"}},
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("groups = %+v, want %+v", groups, want)
+	}
+	// formatSplitPlan 的输出可以在编辑器中修改后再次解析
+	again, err := parseSplitPlan(formatSplitPlan(groups), staged)
+	if err != nil || !reflect.DeepEqual(again, want) {
+		t.Errorf("round trip = %+v, %v", again, err)
+	}
+}
+
+func TestParseSplitPlanErrors(t *testing.T) {
+	staged := []string{"a.go", "b.go"}
+	tests := []struct {
+		plan, want string
+	}{
+		{"a.go\n[1] feat: a\nb.go", "appears before the first commit"},
+		{"[1] feat: a\na.go\nb.go\nc.go", "c.go is not staged"},
+		{"[1] feat: a\na.go\nb.go\n[2] fix: b\nb.go", "b.go appears in more than one commit"},
+		{"[1] feat: a\na.go", "files missing from the plan: b.go"},
+		{"", "files missing from the plan: a.go, b.go"},
+	}
+	for _, tt := range tests {
+		_, err := parseSplitPlan(tt.plan, staged)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseSplitPlan(%q) error = %v, want %q", tt.plan, err, tt.want)
+		}
+	}
+	if _, err := parseSplitPlan("[1] feat: a", nil); err == nil || err.Error() != "empty plan" {
+		t.Errorf("parseSplitPlan without files error = %v, want empty plan", err)
+	}
+}
+
+func TestUnstageAllOnUnbornHead(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return string(out)
+	}
+	git("init", "-q")
+	// 非 ASCII 的路径在没有 -z 时会被加上引号和八进制转义
+	for _, file := range []string{"main.go", "文档.md"} {
+		if err := os.WriteFile(file, []byte("x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", ".")
+	names, err := runCommand("git", stagedDiffArgs("--name-only", "-z")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := splitNull(names), []string{"main.go", "文档.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("staged = %q, want %q", got, want)
+	}
+	files := parseDiff(git("-c", "core.quotepath=true", "diff", "--cached"))
+	if len(files) != 2 || files[1].Path != "文档.md" {
+		t.Errorf("parsed paths = %+v", files)
+	}
+
+	if err := unstageAll(); err != nil {
+		t.Fatal(err)
+	}
+	if staged := git("diff", "--cached", "--name-only"); staged != "" {
+		t.Errorf("still staged after unstageAll: %q", staged)
+	}
+}
@@ -0,0 +1,291 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var usageDays int
+
+func init() {
+	usageCmd.Flags().IntVarP(&usageDays, "days", "d", 30, "Only include usage from the last N days, 0 for all")
+	AICmd.AddCommand(usageCmd)
+}
+
+var AICmd = &cobra.Command{
+	Use:   "ai",
+	Short: "Inspect AI usage of gitx",
+}
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Summarise AI token usage and cost per day, repo and model",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := readUsageLog(usageLogPath())
+		if err != nil {
+			errLog("failed to read usage log: %v", err)
+		}
+		var since time.Time
+		if usageDays > 0 {
+			since = time.Now().AddDate(0, 0, -usageDays)
+		}
+		printUsageReport(entries, since)
+	},
+}
+
+// tokenUsage 是一次请求消耗的 token，后端通过 recordUsage 上报实际值
+type tokenUsage struct {
+	Model        string
+	InputTokens  int
+	OutputTokens int
+}
+
+type usageContextKey struct{}
+
+// withUsage 返回可以记录 token 用量的 context，Provider 接口不需要为此修改
+func withUsage(ctx context.Context) (context.Context, *tokenUsage) {
+	usage := &tokenUsage{}
+	return context.WithValue(ctx, usageContextKey{}, usage), usage
+}
+
+// recordUsage 由后端在收到响应后调用，上报实际的 token 用量
+func recordUsage(ctx context.Context, usage tokenUsage) {
+	if u, ok := ctx.Value(usageContextKey{}).(*tokenUsage); ok {
+		*u = usage
+	}
+}
+
+// estimateTokens 粗略估算 token 数：ASCII 字符约 4 个一个 token，其他字符（中日韩文字等）每个约一个 token
+func estimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+3)/4 + other
+}
+
+// estimateCost 按 input_price 和 output_price（每百万 token 的美元价格）计算费用
+func estimateCost(cfg ProviderConfig, input, output int) float64 {
+	return (float64(input)*cfg.InputPrice + float64(output)*cfg.OutputPrice) / 1e6
+}
+
+// estimateRequests 估算使用同一个系统提示词的多个请求的输入 token 总数，系统提示词每次请求都会发送
+func estimateRequests(system string, users ...string) int {
+	tokens := 0
+	for _, user := range users {
+		tokens += estimateTokens(system) + estimateTokens(user)
+	}
+	return tokens
+}
+
+// confirmTokens 在发送前提示本次运行估算的 token 数和实际会调用的后端的费用，
+// 超过 ai.confirm_tokens 时需要确认，-y 时只提示，每次运行只提示一次
+func confirmTokens(gen *aiGenerator, tokens, requests int) error {
+	if gen.confirmed {
+		return nil
+	}
+	gen.confirmed = true
+	name, cfg := gen.available()
+	estimate := fmt.Sprintf("~%d input tokens to [%s]", tokens, name)
+	if requests > 1 {
+		estimate = fmt.Sprintf("~%d input tokens in %d requests to [%s]", tokens, requests, name)
+	}
+	if cfg.InputPrice > 0 {
+		estimate += fmt.Sprintf(" (~$%.4f)", estimateCost(cfg, tokens, 0))
+	}
+	threshold := config.AI.ConfirmTokens
+	if threshold <= 0 || tokens <= threshold {
+		successLog("Sending %s", estimate)
+		return nil
+	}
+	if aiConfirm {
+		warningLog("Sending %s, above ai.confirm_tokens (%d)", estimate, threshold)
+		return nil
+	}
+	confirm := promptui.Prompt{
+		Label:     fmt.Sprintf("Send %s, above ai.confirm_tokens (%d)", estimate, threshold),
+		IsConfirm: true,
+	}
+	if _, err := confirm.Run(); err != nil {
+		return errCommitAborted
+	}
+	return nil
+}
+
+// usageLogEntry 是 ~/.gitx/usage.jsonl 中的一行
+type usageLogEntry struct {
+	Time         time.Time `json:"time"`
+	Repo         string    `json:"repo"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	// Estimated 表示后端没有返回用量，token 数是估算的
+	Estimated bool    `json:"estimated,omitempty"`
+	Cost      float64 `json:"cost,omitempty"`
+}
+
+func usageLogPath() string {
+	return path.Join(path.Dir(getConfigFilePath()), "usage.jsonl")
+}
+
+// usageRepo 返回记录用量时使用的仓库名，优先使用 origin 的 owner/repo，其次是仓库目录名
+func usageRepo() string {
+	if remote, err := runCommand("git", "remote", "get-url", "origin"); err == nil {
+		if m := regexpGitRepo.FindStringSubmatch(remote); m != nil {
+			return m[1]
+		}
+		remote = strings.TrimSuffix(remote, ".git")
+		if idx := strings.Index(remote, "://"); idx >= 0 {
+			if parts := strings.SplitN(remote[idx+3:], "/", 2); len(parts) == 2 {
+				return parts[1]
+			}
+		}
+	}
+	if root, err := runCommand("git", "rev-parse", "--show-toplevel"); err == nil {
+		return path.Base(root)
+	}
+	return ""
+}
+
+// logUsage 追加一条用量记录，后端没有上报用量时使用估算值，写入失败不影响提交
+func logUsage(repo, provider string, cfg ProviderConfig, usage tokenUsage, system, user, response string) {
+	entry := usageLogEntry{
+		Time:         time.Now(),
+		Repo:         repo,
+		Provider:     provider,
+		Model:        usage.Model,
+		InputTokens:  usage.InputTokens,
+		OutputTokens: usage.OutputTokens,
+	}
+	if entry.Model == "" {
+		entry.Model = cfg.Model
+	}
+	if entry.InputTokens == 0 && entry.OutputTokens == 0 {
+		entry.InputTokens = estimateTokens(system) + estimateTokens(user)
+		entry.OutputTokens = estimateTokens(response)
+		entry.Estimated = true
+	}
+	entry.Cost = estimateCost(cfg, entry.InputTokens, entry.OutputTokens)
+	if isDebug {
+		log.Printf("usage: %+v\n", entry)
+	}
+	if err := appendUsageLog(usageLogPath(), entry); err != nil {
+		warningLog("write usage log fail: %v", err)
+	}
+}
+
+// appendUsageLog 追加一条用量记录，首次使用时 ~/.gitx 可能还不存在
+func appendUsageLog(file string, entry usageLogEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(file), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+func readUsageLog(file string) ([]usageLogEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	var entries []usageLogEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry usageLogEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// usageSummary 是按天、仓库和模型汇总后的用量
+type usageSummary struct {
+	Day, Repo, Model string
+	Requests         int
+	InputTokens      int
+	OutputTokens     int
+	Cost             float64
+	Estimated        bool
+}
+
+func printUsageReport(entries []usageLogEntry, since time.Time) {
+	groups := map[string]*usageSummary{}
+	total := &usageSummary{Day: "TOTAL"}
+	for _, e := range entries {
+		if e.Time.Before(since) {
+			continue
+		}
+		s := usageSummary{Day: e.Time.Local().Format("2006-01-02"), Repo: e.Repo, Model: e.Model}
+		key := s.Day + "\x00" + s.Repo + "\x00" + s.Model
+		if groups[key] == nil {
+			groups[key] = &s
+		}
+		for _, g := range []*usageSummary{groups[key], total} {
+			g.Requests++
+			g.InputTokens += e.InputTokens
+			g.OutputTokens += e.OutputTokens
+			g.Cost += e.Cost
+			g.Estimated = g.Estimated || e.Estimated
+		}
+	}
+	if len(groups) == 0 {
+		warningLog("No AI usage recorded in %s", usageLogPath())
+		return
+	}
+	rows := make([]*usageSummary, 0, len(groups))
+	for _, g := range groups {
+		rows = append(rows, g)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Day != rows[j].Day {
+			return rows[i].Day > rows[j].Day
+		}
+		if rows[i].Repo != rows[j].Repo {
+			return rows[i].Repo < rows[j].Repo
+		}
+		return rows[i].Model < rows[j].Model
+	})
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DAY\tREPO\tMODEL\tREQUESTS\tINPUT\tOUTPUT\tCOST")
+	for _, r := range append(rows, total) {
+		mark := ""
+		if r.Estimated {
+			mark = "~"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s%d\t%s%d\t$%.4f\n", r.Day, r.Repo, r.Model, r.Requests, mark, r.InputTokens, mark, r.OutputTokens, r.Cost)
+	}
+	w.Flush()
+}
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLogUsageCreatesConfigDir(t *testing.T) {
+	// 首次安装时还没有 ~/.gitx，用量记录不能被丢弃
+	t.Setenv("HOME", t.TempDir())
+	logUsage("gitx", "ollama", ProviderConfig{Model: "qwen3.5:4b"}, tokenUsage{InputTokens: 412, OutputTokens: 9}, "system", "diff", "feat: x")
+	logUsage("gitx", "openai", ProviderConfig{Model: "gpt-5.1"}, tokenUsage{}, "system", "diff", "feat: y")
+
+	entries, err := readUsageLog(usageLogPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d usage entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.Provider != "ollama" || e.Model != "qwen3.5:4b" || e.InputTokens != 412 || e.OutputTokens != 9 || e.Estimated {
+		t.Errorf("entries[0] = %+v", e)
+	}
+	if e := entries[1]; e.Model != "gpt-5.1" || !e.Estimated || e.InputTokens == 0 {
+		t.Errorf("entries[1] = %+v", e)
+	}
+}
+
+func TestDiffMessageConfirmsOnce(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	// openai 没有 key，实际会调用的是 ollama，费用按 ollama 的价格计算
+	t.Setenv("OPENAI_API_KEY", "")
+	gen := &aiGenerator{
+		chain: []string{"openai", "ollama"},
+		resolve: func(name string) ProviderConfig {
+			if name == "ollama" {
+				return ProviderConfig{InputPrice: 1e6}
+			}
+			return ProviderConfig{}
+		},
+	}
+	diff := "diff --git a/app.go b/app.go\n--- a/app.go\n+++ b/app.go\n@@ -1 +1 @@\n-a\n+key := \"" + fakeAWSKey + "\"\n"
+	system := strings.Repeat("x", 4000)
+	user, err := diffMessage(context.Background(), gen, diff, system, system)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := estimateRequests(system, user, user)
+	if tokens < 2000 {
+		t.Fatalf("estimate %d does not include the system prompt of both requests", tokens)
+	}
+	want := fmt.Sprintf("Sending ~%d input tokens in 2 requests to [ollama] (~$%.4f)", tokens, float64(tokens))
+	if !strings.Contains(out.String(), want) || !strings.Contains(out.String(), "aws-access-key-id") {
+		t.Errorf("log = %q, want %q and the secret location", out.String(), want)
+	}
+
+	// --split 的每一组只替换密钥，不再重复提示
+	out.Reset()
+	again, err := diffMessage(context.Background(), gen, diff, system)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 || strings.Contains(again, fakeAWSKey) {
+		t.Errorf("second call logged %q, message %q", out.String(), again)
+	}
+}
@@ -0,0 +1,258 @@
+package commands
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+//go:embed prompts/changelog.prompt
+var changelogPrompt string
+
+// changelogSections 是更新日志中各类型的标题和顺序，未列出的类型归入 Other Changes
+var changelogSections = []struct {
+	Type  string
+	Title string
+}{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance Improvements"},
+	{"revert", "Reverts"},
+	{"refactor", "Code Refactoring"},
+	{"docs", "Documentation"},
+	{"i18n", "Internationalization"},
+	{"style", "Styles"},
+	{"test", "Tests"},
+	{"build", "Build System"},
+	{"ci", "Continuous Integration"},
+	{"chore", "Chores"},
+}
+
+const changelogFileTitle = "# Changelog"
+
+var (
+	changelogTitle string
+	changelogWrite bool
+	changelogFile  string
+	changelogAI    bool
+)
+
+func init() {
+	ChangelogCmd.Flags().StringVarP(&changelogTitle, "title", "t", "", "Set the release title, default is <to> or 'Unreleased' without <to>")
+	ChangelogCmd.Flags().BoolVarP(&changelogWrite, "write", "w", false, "Prepend the changelog to the changelog file instead of printing it")
+	ChangelogCmd.Flags().StringVarP(&changelogFile, "file", "", "CHANGELOG.md", "Set the changelog file used by --write")
+	ChangelogCmd.Flags().BoolVarP(&changelogAI, "ai", "", false, "Let AI rewrite the changelog into user-facing release notes")
+	bindAIFlags(ChangelogCmd, "release notes")
+}
+
+// changelogEntry 是更新日志中的一个提交
+type changelogEntry struct {
+	Hash     string
+	Type     string
+	Scope    string
+	Subject  string
+	Breaking string
+}
+
+var ChangelogCmd = &cobra.Command{
+	Use:   "changelog <from> [to]",
+	Short: "Generate a changelog grouped by commit type between two refs",
+	Long:  "Generate a Markdown changelog from the commits in <from>..<to> (default HEAD), grouped by Conventional Commits type.",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		from, to := rangeRef(args[0]), "HEAD"
+		if len(args) > 1 {
+			to = rangeRef(args[1])
+		}
+		entries, err := changelogEntries(from, to)
+		if err != nil {
+			errLog("git log fail: %v", err)
+		}
+		if len(entries) == 0 {
+			errLog("No commits between %s and %s.", from, to)
+		}
+		title := changelogTitle
+		if title == "" {
+			title = "Unreleased"
+			if len(args) > 1 {
+				title = args[1]
+			}
+		}
+		date, err := runCommand("git", "log", "-1", "--format=%cs", to)
+		if err != nil {
+			errLog("git log fail: %v", err)
+		}
+		changelog := renderChangelog(title, date, entries)
+		if changelogAI {
+			changelog = rewriteChangelog(cmd, changelog)
+		}
+		if !changelogWrite {
+			fmt.Print(changelog)
+			return
+		}
+		if err := prependChangelog(changelogFile, changelog); err != nil {
+			errLog("Write %s fail: %v", changelogFile, err)
+		}
+		successLog("Changelog of %d commits written to %s", len(entries), changelogFile)
+	},
+}
+
+// changelogEntries 读取 from..to 之间的提交，跳过合并提交，
+// 不符合 Conventional Commits 格式的提交类型为空
+func changelogEntries(from, to string) ([]changelogEntry, error) {
+	output, err := runCommand("git", "log", "--no-merges", "--format=%h%x00%B%x1e", from+".."+to)
+	if err != nil {
+		return nil, err
+	}
+	var entries []changelogEntry
+	for _, record := range strings.Split(output, "\x1e") {
+		hash, msg, ok := strings.Cut(strings.TrimSpace(record), "\x00")
+		if !ok {
+			continue
+		}
+		entries = append(entries, parseChangelogEntry(hash, msg))
+	}
+	return entries, nil
+}
+
+// parseChangelogEntry 解析单个提交信息，不符合 Conventional Commits 格式时类型为空
+func parseChangelogEntry(hash, msg string) changelogEntry {
+	subject, body, _ := strings.Cut(strings.TrimSpace(msg), "\n")
+	entry := changelogEntry{Hash: hash, Subject: subject}
+	// github 模板生成的提交带有 emoji 前缀，例如 "✨ feat: ..."，类型只从第一行读取
+	_, header := splitHeaderPrefix(subject)
+	if body != "" {
+		header += "\n" + body
+	}
+	c, err := parseConventionalCommit(header)
+	if err != nil || c.Description == "" {
+		return entry
+	}
+	entry.Type, entry.Scope, entry.Subject = c.Type, c.Scope, c.Description
+	if c.isBreaking() {
+		entry.Breaking = c.Description
+		for _, f := range c.Footers {
+			if f.Token == "BREAKING CHANGE" || f.Token == "BREAKING-CHANGE" {
+				entry.Breaking = strings.TrimSpace(f.Value)
+			}
+		}
+	}
+	return entry
+}
+
+// renderChangelog 按类型分组生成 Markdown，破坏性变更单独列在最前面
+func renderChangelog(title, date string, entries []changelogEntry) string {
+	var sb strings.Builder
+	sb.WriteString("## " + title)
+	if date != "" {
+		sb.WriteString(" (" + date + ")")
+	}
+	sb.WriteString("\n")
+	section := func(name string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		sb.WriteString("\n### " + name + "\n\n")
+		for _, line := range lines {
+			sb.WriteString("- " + line + "\n")
+		}
+	}
+	item := func(e changelogEntry, text string) string {
+		if e.Scope != "" {
+			text = "**" + e.Scope + ":** " + text
+		}
+		return text + " (" + e.Hash + ")"
+	}
+
+	var breaking []string
+	for _, e := range entries {
+		if e.Breaking != "" {
+			breaking = append(breaking, item(e, e.Breaking))
+		}
+	}
+	section("⚠ BREAKING CHANGES", breaking)
+	known := make([]string, 0, len(changelogSections))
+	for _, s := range changelogSections {
+		known = append(known, s.Type)
+		var lines []string
+		for _, e := range entries {
+			if e.Type == s.Type {
+				lines = append(lines, item(e, e.Subject))
+			}
+		}
+		section(s.Title, lines)
+	}
+	var other []string
+	for _, e := range entries {
+		if !slices.Contains(known, e.Type) {
+			other = append(other, item(e, e.Subject))
+		}
+	}
+	section("Other Changes", other)
+	return sb.String()
+}
+
+// rewriteChangelog 让 AI 将按类型分组的列表改写为面向用户的发布说明
+func rewriteChangelog(cmd *cobra.Command, changelog string) string {
+	sp, err := renderPrompt("builtin/changelog", changelogPrompt, promptData{Language: languageName(commitLanguage(cmd))})
+	if err != nil {
+		errLog("Render prompt fail: %v", err)
+	}
+	gen := newAIGenerator(cmd)
+	if err := confirmTokens(gen, estimateRequests(sp, changelog), 1); err != nil {
+		errLog("%v", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	msg, agent, err := gen.generate(ctx, sp, changelog, func(msg string) error {
+		if strings.TrimSpace(stripCodeFence(msg)) == "" {
+			return errors.New("empty release notes")
+		}
+		return nil
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			errLog("Canceled.")
+		}
+		errLog("Generate release notes fail: %v", err)
+	}
+	successLog("Release notes generated by [%s]", agent)
+	return strings.TrimSpace(stripCodeFence(msg)) + "\n"
+}
+
+// stripCodeFence 去掉 AI 输出中包裹整段内容的代码块标记
+func stripCodeFence(msg string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(msg, "\r\n", "\n"), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "```") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// prependChangelog 将新的版本插入到更新日志文件的开头，文件以 "# Changelog" 标题开头时插入到标题之后
+func prependChangelog(file, changelog string) error {
+	data, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	content := strings.TrimLeft(string(data), "\n")
+	head := changelogFileTitle + "\n\n"
+	if first, rest, _ := strings.Cut(content, "\n"); strings.HasPrefix(first, "# ") {
+		head = first + "\n\n"
+		content = strings.TrimLeft(rest, "\n")
+	}
+	result := head + changelog
+	if content != "" {
+		result += "\n" + content
+	}
+	return os.WriteFile(file, []byte(result), 0644)
+}
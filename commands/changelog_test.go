@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"os"
+	"testing"
+)
+
+func TestRenderChangelogGroupsByType(t *testing.T) {
+	var entries []changelogEntry
+	for _, c := range []struct{ hash, msg string }{
+		{"a1", "feat(api): add users endpoint"},
+		{"b2", "i18n: add zh translations"},
+		{"c3", "🌐 i18n(ui): translate settings page"},
+		{"d4", "fix: handle nil config"},
+		{"e5", "update readme"},
+		{"g7", "合并代码\n\nfeat: something"},
+		{"h8", "合并 fix: typo"},
+		{"f6", "refactor(db): split pool\n\nBREAKING CHANGE: pool size option removed"},
+	} {
+		entries = append(entries, parseChangelogEntry(c.hash, c.msg))
+	}
+	got := renderChangelog("v1.0.0", "2026-10-18", entries)
+	want := `## v1.0.0 (2026-10-18)
+
+### ⚠ BREAKING CHANGES
+
+- **db:** pool size option removed (f6)
+
+### Features
+
+- **api:** add users endpoint (a1)
+
+### Bug Fixes
+
+- handle nil config (d4)
+
+### Code Refactoring
+
+- **db:** split pool (f6)
+
+### Internationalization
+
+- add zh translations (b2)
+- **ui:** translate settings page (c3)
+
+### Other Changes
+
+- update readme (e5)
+- 合并代码 (g7)
+- 合并 fix: typo (h8)
+`
+	if got != want {
+		t.Errorf("renderChangelog() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPrependChangelog(t *testing.T) {
+	file := t.TempDir() + "/CHANGELOG.md"
+	if err := prependChangelog(file, "## v1\n\n- one\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := prependChangelog(file, "## v2\n\n- two\n"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Changelog\n\n## v2\n\n- two\n\n## v1\n\n- one\n"
+	if string(data) != want {
+		t.Errorf("CHANGELOG.md = %q, want %q", data, want)
+	}
+}
@@ -60,6 +60,7 @@ var CloneCmd = &cobra.Command{
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		resolveMainBranch(cmd, &mainBranch)
 		repoURL := args[0]
 		version := args[1]
 		branch := args[2]
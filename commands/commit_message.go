@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	defaultSubjectMaxLength = 100
+	defaultBodyWrap         = 72
+)
+
+// subjectMaxLength 返回提交标题的最大长度，am.subject_max_length 小于 0 时不限制
+func subjectMaxLength() int {
+	if config.AM.SubjectMaxLength != 0 {
+		return config.AM.SubjectMaxLength
+	}
+	return defaultSubjectMaxLength
+}
+
+// bodyWrap 返回正文的换行宽度，am.body_wrap 小于 0 时不换行
+func bodyWrap() int {
+	if config.AM.BodyWrap != 0 {
+		return config.AM.BodyWrap
+	}
+	return defaultBodyWrap
+}
+
+// checkSubjectLength 检查标题长度，按字符计算
+func checkSubjectLength(commit *conventionalCommit) error {
+	limit := subjectMaxLength()
+	if n := utf8.RuneCountInString(commit.Header()); limit > 0 && n > limit {
+		return fmt.Errorf("commit subject is too long (%d > %d characters)", n, limit)
+	}
+	return nil
+}
+
+// setTrailer 设置 footer，已存在同名 footer 时覆盖
+func setTrailer(commit *conventionalCommit, token, value string) {
+	for i, f := range commit.Footers {
+		if strings.EqualFold(f.Token, token) {
+			commit.Footers[i].Value = value
+			return
+		}
+	}
+	commit.Footers = append(commit.Footers, conventionalFooter{Token: token, Separator: ": ", Value: value})
+}
+
+// buildCommitMessage 生成完整的提交信息：标题、按宽度换行的正文以及 trailers
+func buildCommitMessage(commit *conventionalCommit, width int) string {
+	wrapped := *commit
+	wrapped.Body = wrapText(commit.Body, width)
+	return wrapped.String() + "\n"
+}
+
+// commitWithMessage 将提交信息写入临时文件并执行 git commit -F
+func commitWithMessage(msg string, extraArgs ...string) error {
+	f, err := os.CreateTemp("", "gitx-commit-msg-*.txt")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(msg); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	args := append([]string{"commit", "--cleanup=whitespace", "-F", f.Name()}, extraArgs...)
+	output, err := runCommand("git", args...)
+	if output != "" {
+		fmt.Fprintln(os.Stderr, output)
+	}
+	return err
+}
+
+// wrapText 按 width 对每一行换行，列表项的续行与列表内容对齐，width 小于等于 0 时不换行
+func wrapText(text string, width int) string {
+	if width <= 0 || text == "" {
+		return text
+	}
+	lines := strings.Split(text, "\n")
+	var out []string
+	for _, line := range lines {
+		out = append(out, wrapLine(line, width)...)
+	}
+	return strings.Join(out, "\n")
+}
+
+func wrapLine(line string, width int) []string {
+	if displayWidth(line) <= width {
+		return []string{line}
+	}
+	indent := listIndent(line)
+	var lines []string
+	var cur strings.Builder
+	curWidth := 0
+	for _, word := range splitWords(line[len(indent):]) {
+		w := displayWidth(word)
+		// 片段末尾的空格在换行时会被去掉，不占用宽度
+		if curWidth > 0 && curWidth+displayWidth(strings.TrimRight(word, " ")) > width {
+			lines = append(lines, strings.TrimRight(cur.String(), " "))
+			cur.Reset()
+			curWidth = 0
+		}
+		if curWidth == 0 {
+			if len(lines) == 0 {
+				cur.WriteString(indent)
+			} else {
+				cur.WriteString(strings.Repeat(" ", displayWidth(indent)))
+			}
+			curWidth = displayWidth(indent)
+			word = strings.TrimLeft(word, " ")
+			w = displayWidth(word)
+		}
+		cur.WriteString(word)
+		curWidth += w
+	}
+	if cur.Len() > 0 {
+		lines = append(lines, strings.TrimRight(cur.String(), " "))
+	}
+	return lines
+}
+
+// listIndent 返回行首的缩进和列表标记，例如 "  - " 或 "1. "
+func listIndent(line string) string {
+	trimmed := strings.TrimLeft(line, " \t")
+	indent := line[:len(line)-len(trimmed)]
+	if loc := regexpListMarker.FindStringIndex(trimmed); loc != nil {
+		return indent + trimmed[:loc[1]]
+	}
+	return indent
+}
+
+// splitWords 将一行拆分为可以换行的片段，空格跟随在前一个片段之后，中日韩文字每个字符是一个片段
+func splitWords(s string) []string {
+	var words []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			words = append(words, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, r := range s {
+		switch {
+		case r == ' ':
+			cur.WriteRune(r)
+			flush()
+		case isWide(r):
+			flush()
+			words = append(words, string(r))
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+	return words
+}
+
+// displayWidth 返回终端中的显示宽度，中日韩文字占两列
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		if isWide(r) {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+func isWide(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303F) || (r >= 0xFF00 && r <= 0xFFEF)
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width int
+		want  string
+	}{
+		{
+			name:  "short line",
+			text:  "fix the login redirect",
+			width: 72,
+			want:  "fix the login redirect",
+		},
+		{
+			name:  "ascii words",
+			text:  "the quick brown fox jumps over the lazy dog",
+			width: 20,
+			want:  "the quick brown fox\njumps over the lazy\ndog",
+		},
+		{
+			name:  "cjk counts two columns",
+			text:  "修复登录后跳转到错误页面的问题",
+			width: 20,
+			want:  "修复登录后跳转到错误\n页面的问题",
+		},
+		{
+			name:  "mixed cjk and ascii",
+			text:  "使用 OAuth 2.0 登录并缓存 access token",
+			width: 20,
+			want:  "使用 OAuth 2.0 登录\n并缓存 access token",
+		},
+		{
+			name:  "long url is not split",
+			text:  "see https://example.com/a/very/long/path/that/does/not/fit/in/the/width for details",
+			width: 30,
+			want:  "see\nhttps://example.com/a/very/long/path/that/does/not/fit/in/the/width\nfor details",
+		},
+		{
+			name:  "list continuation is aligned",
+			text:  "- add the users endpoint with pagination\n10. drop the deprecated v1 routes",
+			width: 24,
+			want:  "- add the users endpoint\n  with pagination\n10. drop the deprecated\n    v1 routes",
+		},
+		{
+			name:  "word ending at width",
+			text:  "fix the login redirect loop",
+			width: 22,
+			want:  "fix the login redirect\nloop",
+		},
+		{
+			name:  "disabled",
+			text:  "the quick brown fox jumps over the lazy dog",
+			width: 0,
+			want:  "the quick brown fox jumps over the lazy dog",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapText(tt.text, tt.width)
+			if got != tt.want {
+				t.Errorf("wrapText() =\n%s\nwant:\n%s", got, tt.want)
+			}
+			for _, line := range strings.Split(got, "\n") {
+				if tt.width > 0 && displayWidth(line) > tt.width && !strings.Contains(line, "https://") {
+					t.Errorf("line %q is wider than %d", line, tt.width)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildCommitMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		msg     string
+		trailer string
+		want    string
+	}{
+		{
+			name:    "trailer appended",
+			msg:     "feat: add users endpoint\n\nthe endpoint supports pagination and filtering by role",
+			trailer: "PROJ-42",
+			want:    "feat: add users endpoint\n\nthe endpoint supports pagination and filtering by\nrole\n\nRefs: PROJ-42\n",
+		},
+		{
+			name:    "existing refs replaced",
+			msg:     "fix: handle nil config\n\nRefs: PROJ-1",
+			trailer: "PROJ-42",
+			want:    "fix: handle nil config\n\nRefs: PROJ-42\n",
+		},
+		{
+			name:    "body ending in trailers",
+			msg:     "feat: 支持多语言\n\n添加中文和日文的翻译文件，并在设置页面中增加语言切换的选项\n\nReviewed-by: Alice\nrefs: PROJ-1",
+			trailer: "PROJ-42, PROJ-43",
+			want:    "feat: 支持多语言\n\n添加中文和日文的翻译文件，并在设置页面中增加语言切\n换的选项\n\nReviewed-by: Alice\nrefs: PROJ-42, PROJ-43\n",
+		},
+		{
+			name: "long trailer is not wrapped",
+			msg:  "docs: link the design doc\n\nSee-also: https://example.com/a/very/long/path/that/does/not/fit/in/the/width",
+			want: "docs: link the design doc\n\nSee-also: https://example.com/a/very/long/path/that/does/not/fit/in/the/width\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commit, err := parseConventionalCommit(tt.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.trailer != "" {
+				setTrailer(commit, "Refs", tt.trailer)
+			}
+			if got := buildCommitMessage(commit, 50); got != tt.want {
+				t.Errorf("buildCommitMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -70,70 +72,171 @@ var ConfigCmd = &cobra.Command{
 		}
 		switch args[0] {
 		case "view":
-			if !isDirExist {
-				warningLog("config file does not exist, please run 'gitx config' to create one")
+			if configShowOrigin {
+				printConfigOrigins()
 				return
 			}
-			data, err := os.ReadFile(storePath)
+			data, err := json.MarshalIndent(configTree, "", "  ")
 			if err != nil {
-				errLog("failed to read config file: %v", err)
+				errLog("failed to serialize config: %v", err)
 			}
 			fmt.Println(string(data))
 		case "set":
 			key := args[1]
 			value := args[2]
-			var cfg Config
-			if !isDirExist {
-				data, err := os.ReadFile(storePath)
-				if err != nil {
-					errLog("failed to read config file: %v", err)
-				}
-				if err := json.Unmarshal(data, &cfg); err != nil {
-					errLog("failed to parse config file: %v", err)
-				}
-			}
-			switch key {
-			case "workspace_dir":
-				cfg.WorkspaceDir = value
-			case "default_ide":
-				cfg.DefaultIDE = value
-			case "open_in_ide_after_use":
-				if strings.ToLower(value) == "true" {
-					cfg.OpenInIDEAfterUse = true
-				} else if strings.ToLower(value) == "false" {
-					cfg.OpenInIDEAfterUse = false
-				} else {
-					errLog("invalid value for open_in_ide_after_use: %s", value)
-				}
-			case "common_projects":
-				projects := strings.Split(value, ",")
-				for i := range projects {
-					projects[i] = strings.TrimSpace(projects[i])
+			file := storePath
+			if configRepo {
+				file = repoConfigFile()
+				if file == "" {
+					errLog("not in a git repository")
 				}
-				cfg.CommonProjects = projects
-			case "prefix":
-				prefixes := strings.Split(value, ",")
-				for i := range prefixes {
-					prefixes[i] = strings.TrimSpace(prefixes[i])
-				}
-				cfg.Prefix = prefixes
-			}
-			if cfg.Prefix == nil {
-				cfg.Prefix = []string{}
 			}
-			if cfg.CommonProjects == nil {
-				cfg.CommonProjects = []string{}
+			if err := setConfigFile(file, key, value); err != nil {
+				errLog("%v", err)
 			}
-			updatedData, err := json.MarshalIndent(cfg, "", "  ")
-			if err != nil {
-				errLog("failed to serialize config: %v", err)
+			successLog("Configuration updated in %s: %s set to %s", file, key, value)
+		}
+	},
+}
+
+// setConfigValue 解析字符串形式的配置值并写入 cfg，config set、GITX_* 环境变量和 -c 共用
+func setConfigValue(cfg *Config, key, value string) error {
+	switch key {
+	case "workspace_dir":
+		cfg.WorkspaceDir = value
+	case "default_ide":
+		cfg.DefaultIDE = value
+	case "open_in_ide_after_use":
+		if strings.ToLower(value) == "true" {
+			cfg.OpenInIDEAfterUse = true
+		} else if strings.ToLower(value) == "false" {
+			cfg.OpenInIDEAfterUse = false
+		} else {
+			return fmt.Errorf("invalid value for open_in_ide_after_use: %s", value)
+		}
+	case "common_projects":
+		projects := strings.Split(value, ",")
+		for i := range projects {
+			projects[i] = strings.TrimSpace(projects[i])
+		}
+		cfg.CommonProjects = projects
+	case "prefix":
+		prefixes := strings.Split(value, ",")
+		for i := range prefixes {
+			prefixes[i] = strings.TrimSpace(prefixes[i])
+		}
+		cfg.Prefix = prefixes
+	case "main_branch":
+		cfg.MainBranch = value
+	case "ai.provider":
+		cfg.AI.Provider = value
+	case "ai.fallback":
+		fallback := strings.Split(value, ",")
+		for i := range fallback {
+			fallback[i] = strings.TrimSpace(fallback[i])
+		}
+		cfg.AI.Fallback = fallback
+	case "ai.timeout":
+		timeout, err := strconv.Atoi(value)
+		if err != nil || timeout < 0 {
+			return fmt.Errorf("invalid value for ai.timeout: %s", value)
+		}
+		cfg.AI.Timeout = timeout
+	case "ai.language":
+		cfg.AI.Language = value
+	case "ai.types":
+		types := strings.Split(value, ",")
+		for i := range types {
+			types[i] = strings.TrimSpace(types[i])
+		}
+		cfg.AI.Types = types
+	case "am.after_commit":
+		if !slices.Contains(afterCommitModes, value) {
+			return fmt.Errorf("invalid value for am.after_commit: %s (available: %s)", value, strings.Join(afterCommitModes, "|"))
+		}
+		cfg.AM.AfterCommit = value
+	case "am.subject_max_length", "am.body_wrap":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %s", key, value)
+		}
+		if key == "am.subject_max_length" {
+			cfg.AM.SubjectMaxLength = n
+		} else {
+			cfg.AM.BodyWrap = n
+		}
+	case "am.ticket_pattern":
+		if _, err := regexp.Compile(value); err != nil {
+			return fmt.Errorf("invalid value for am.ticket_pattern: %v", err)
+		}
+		cfg.AM.TicketPattern = value
+	case "am.ticket_trailer":
+		if !slices.Contains(ticketTrailers, value) {
+			return fmt.Errorf("invalid value for am.ticket_trailer: %s (available: %s)", value, strings.Join(ticketTrailers, "|"))
+		}
+		cfg.AM.TicketTrailer = value
+	case "am.require_ticket":
+		require, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for am.require_ticket: %s", value)
+		}
+		cfg.AM.RequireTicket = require
+	case "am.exclude":
+		exclude := strings.Split(value, ",")
+		for i := range exclude {
+			exclude[i] = strings.TrimSpace(exclude[i])
+		}
+		cfg.AM.Exclude = exclude
+	case "ai.secrets.mode":
+		if !slices.Contains(secretsModes, value) {
+			return fmt.Errorf("invalid value for ai.secrets.mode: %s (available: %s)", value, strings.Join(secretsModes, "|"))
+		}
+		cfg.AI.Secrets.Mode = value
+	case "ai.secrets.patterns":
+		// 正则中可能有 {8,32} 这样的逗号，每行一个规则
+		var patterns []string
+		for _, pattern := range strings.Split(value, "\n") {
+			if pattern = strings.TrimSpace(pattern); pattern == "" {
+				continue
 			}
-			if err := os.WriteFile(storePath, updatedData, 0644); err != nil {
-				errLog("failed to write config file: %v", err)
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid value for ai.secrets.patterns: %v", err)
 			}
-			successLog("Configuration updated: %s set to %s", key, value)
+			patterns = append(patterns, pattern)
 		}
-	},
+		cfg.AI.Secrets.Patterns = patterns
+	case "ai.cache.disabled":
+		disabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for ai.cache.disabled: %s", value)
+		}
+		cfg.AI.Cache.Disabled = disabled
+	case "ai.cache.ttl", "ai.cache.max_size":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid value for %s: %s", key, value)
+		}
+		if key == "ai.cache.ttl" {
+			cfg.AI.Cache.TTL = n
+		} else {
+			cfg.AI.Cache.MaxSize = n
+		}
+	case "ai.confirm_tokens":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid value for ai.confirm_tokens: %s", value)
+		}
+		cfg.AI.ConfirmTokens = n
+	case "ai.ignore":
+		ignore := strings.Split(value, ",")
+		for i := range ignore {
+			ignore[i] = strings.TrimSpace(ignore[i])
+		}
+		cfg.AI.Ignore = ignore
+	default:
+		return fmt.Errorf("invalid config key: %s", key)
+	}
+	return nil
 }
 
 var (
@@ -145,22 +248,52 @@ var (
 		"open_in_ide_after_use": true,
 		"common_projects":       true,
 		"prefix":                true,
+		"main_branch":           true,
+		"ai.provider":           true,
+		"ai.fallback":           true,
+		"ai.timeout":            true,
+		"ai.ignore":             true,
+		"ai.language":           true,
+		"am.after_commit":       true,
+		"ai.types":              true,
+		"am.subject_max_length": true,
+		"am.body_wrap":          true,
+		"am.ticket_pattern":     true,
+		"am.ticket_trailer":     true,
+		"am.require_ticket":     true,
+		"am.exclude":            true,
+		"ai.secrets.mode":       true,
+		"ai.secrets.patterns":   true,
+		"ai.cache.disabled":     true,
+		"ai.cache.ttl":          true,
+		"ai.cache.max_size":     true,
+		"ai.confirm_tokens":     true,
 	}
-	prefix = []string{
+	defaultPrefix = []string{
 		"feat",
 		"online-fix",
 		"online-revision",
 	}
-	regexpProject *regexp.Regexp
+	prefix           = defaultPrefix
+	regexpProject    *regexp.Regexp
+	configShowOrigin bool
+	configRepo       bool
 )
 
 func init() {
+	ConfigCmd.Flags().BoolVarP(&configShowOrigin, "show-origin", "", false, "Show where each effective value comes from, used with 'view'")
+	ConfigCmd.Flags().BoolVarP(&configRepo, "repo", "", false, "Write to the repo-local config file instead of ~/.gitx/config.json, used with 'set'")
 	loadConfig()
+	applyConfig()
+}
 
+// applyConfig 根据合并后的配置初始化工作目录和项目目录的匹配规则，-c 覆盖配置后会再次调用
+func applyConfig() {
 	workspaceDir = getHomeDir()
 	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
 		errLog("Make work space dir fail")
 	}
+	prefix = defaultPrefix
 	if len(config.Prefix) > 0 {
 		prefix = config.Prefix
 	}
@@ -170,21 +303,12 @@ func init() {
 	if config.DefaultIDE == "" {
 		config.DefaultIDE = "code"
 	}
-
 }
 
-func loadConfig() {
-	configFilePath := getConfigFilePath()
-	if _, err := os.Stat(configFilePath); os.IsNotExist(err) {
-		warningLog("config file does not exist, please run 'gitx config' to create one")
-		return
-	}
-	data, err := os.ReadFile(configFilePath)
-	if err != nil {
-		errLog("failed to read config file: %v", err)
-	}
-	if err := json.Unmarshal(data, &config); err != nil {
-		errLog("failed to parse config file: %v", err)
+// resolveMainBranch 未指定 --branch 时使用配置中的 main_branch
+func resolveMainBranch(cmd *cobra.Command, branch *string) {
+	if !cmd.Flags().Changed("branch") && config.MainBranch != "" {
+		*branch = config.MainBranch
 	}
 }
 
@@ -195,7 +319,15 @@ var (
 	  "default_ide": "code",
 	  "open_in_ide_after_use": true,
 	  "common_projects": [],
-	  "prefix": ["feat", "fix", "hotfix", "online", "release"]
+	  "prefix": ["feat", "fix", "hotfix", "online", "release"],
+	  "ai": {
+	    "provider": "openai",
+	    "providers": {
+	      "openai": {"model": "gpt-5.1"},
+	      "gemini": {"model": "gemini-2.5-flash"},
+	      "ollama": {"model": "qwen3.5:4b"}
+	    }
+	  }
 }
 `
 )
@@ -206,6 +338,66 @@ type Config struct {
 	OpenInIDEAfterUse bool     `json:"open_in_ide_after_use"`
 	CommonProjects    []string `json:"common_projects"`
 	Prefix            []string `json:"prefix"`
+	MainBranch        string   `json:"main_branch,omitempty"`
+	AI                AIConfig `json:"ai"`
+	AM                AMConfig `json:"am"`
+}
+
+type AMConfig struct {
+	// AfterCommit 是提交后的行为：none|push|pull-push|pull-rebase-push，默认 pull-push
+	AfterCommit string `json:"after_commit,omitempty"`
+	// SubjectMaxLength 是提交标题的最大字符数，默认 100，小于 0 时不限制
+	SubjectMaxLength int `json:"subject_max_length,omitempty"`
+	// BodyWrap 是正文的换行宽度，默认 72，小于 0 时不换行
+	BodyWrap int `json:"body_wrap,omitempty"`
+	// Scopes 是路径前缀到 scope 的映射，例如 {"commands/": "cli", "commands/prompts/": "ai"}
+	Scopes map[string]string `json:"scopes,omitempty"`
+	// TicketPattern 是从分支名中提取 issue 编号的正则，有分组时使用第一个分组，默认 [A-Z][A-Z0-9]+-\d+
+	TicketPattern string `json:"ticket_pattern,omitempty"`
+	// TicketTrailer 是写入 issue 编号的 trailer：Refs|Closes，默认 Refs
+	TicketTrailer string `json:"ticket_trailer,omitempty"`
+	// RequireTicket 为 true 时，分支名中没有 issue 编号的提交会被拒绝
+	RequireTicket bool `json:"require_ticket,omitempty"`
+	// Exclude 是不发送给 AI 的文件，使用 git pathspec 语法，例如 ["*.lock", "docs/api/"]
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+type AIConfig struct {
+	// Provider 是 gitx am 默认使用的后端，可被 --agent 覆盖
+	Provider  string                    `json:"provider,omitempty"`
+	Providers map[string]ProviderConfig `json:"providers,omitempty"`
+	// Fallback 是后端失败时依次尝试的列表，例如 ["ollama", "openai"]
+	Fallback []string `json:"fallback,omitempty"`
+	// Timeout 是单个后端的超时时间，单位秒，默认 60
+	Timeout int `json:"timeout,omitempty"`
+	// Language 是提交信息使用的语言，例如 zh、en，默认 zh
+	Language string `json:"language,omitempty"`
+	// Types 是允许的 Conventional Commits 类型，默认 feat|fix|docs|style|refactor|perf|test|build|ci|chore|revert|i18n
+	Types []string `json:"types,omitempty"`
+	// Ignore 是只发送文件名和改动行数的文件，以 / 结尾表示目录，默认折叠常见的锁文件和第三方代码
+	Ignore []string `json:"ignore,omitempty"`
+	// Secrets 控制发送 diff 前的密钥检测
+	Secrets SecretsConfig `json:"secrets,omitempty"`
+	// Cache 控制 AI 响应的本地缓存
+	Cache CacheConfig `json:"cache,omitempty"`
+	// ConfirmTokens 是需要确认的估算输入 token 数，0 表示不确认
+	ConfirmTokens int `json:"confirm_tokens,omitempty"`
+}
+
+type CacheConfig struct {
+	// Disabled 为 true 时不使用缓存
+	Disabled bool `json:"disabled,omitempty"`
+	// TTL 是缓存的有效期，单位小时，默认 168
+	TTL int `json:"ttl,omitempty"`
+	// MaxSize 是缓存目录的最大大小，单位 MB，默认 50
+	MaxSize int `json:"max_size,omitempty"`
+}
+
+type SecretsConfig struct {
+	// Mode 是发现密钥时的处理方式：redact|block|off，默认 redact
+	Mode string `json:"mode,omitempty"`
+	// Patterns 是追加的自定义正则，例如 ["corp_[a-z0-9]{32}"]
+	Patterns []string `json:"patterns,omitempty"`
 }
 
 func getConfigFilePath() string {
@@ -0,0 +1,391 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// repoConfigFiles 是仓库内的配置文件，按顺序使用第一个存在的文件
+var repoConfigFiles = []string{".gitx.json", ".gitx/config.json"}
+
+// repoDeniedKeys 是仓库内的配置不能设置的后端字段，避免克隆的仓库把 API key 发送到其他地址
+var repoDeniedKeys = []string{"base_url", "api_key_env"}
+
+// repoWarnedKeys 是仓库内的配置修改时需要提示的后端选择，diff 会被发送到新的后端
+var repoWarnedKeys = []string{"provider", "fallback"}
+
+// secretsStrictness 是 ai.secrets.mode 的严格程度，仓库内的配置只能提高
+var secretsStrictness = map[string]int{secretsOff: 0, secretsRedact: 1, secretsBlock: 2}
+
+const originDefault = "default"
+
+// configSource 是一个配置项的值及其来源，例如 file:/home/x/.gitx/config.json、env:GITX_AI_PROVIDER
+type configSource struct {
+	Value  any
+	Origin string
+}
+
+var (
+	// configTree 是合并后的配置，config view 直接输出
+	configTree map[string]any
+	// configOrigins 记录每个配置项最终生效的值和来源，key 为 ai.providers.openai.model 这样的路径
+	configOrigins map[string]configSource
+	// configOverrides 是 -c key=value 指定的配置
+	configOverrides []string
+)
+
+// BindConfigFlags 在根命令上注册 -c key=value，命令执行前按最高优先级覆盖配置
+func BindConfigFlags(root *cobra.Command) {
+	root.PersistentFlags().StringArrayVarP(&configOverrides, "config", "c", nil, "Override a config value for this run, e.g. -c ai.provider=ollama (repeatable)")
+	root.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if len(configOverrides) == 0 {
+			return
+		}
+		for _, override := range configOverrides {
+			key, value, ok := strings.Cut(override, "=")
+			if !ok {
+				errLog("invalid -c %q, expected key=value", override)
+			}
+			if err := overrideConfig(configTree, configOrigins, key, value, "flag:-c"); err != nil {
+				errLog("invalid -c %q: %v", override, err)
+			}
+		}
+		decodeConfig(configTree, configOrigins)
+		applyConfig()
+	}
+}
+
+// defaultConfigValues 是内置的默认配置，与各个配置项在代码中的默认值一致
+func defaultConfigValues() map[string]any {
+	return map[string]any{
+		"default_ide": "code",
+		"prefix":      defaultPrefix,
+		"main_branch": "main",
+		"ai": map[string]any{
+			"timeout":  60,
+			"language": defaultLanguage,
+			"types":    defaultCommitTypes,
+			"secrets":  map[string]any{"mode": secretsRedact},
+			"cache": map[string]any{
+				"ttl":      int(defaultCacheTTL / time.Hour),
+				"max_size": defaultCacheMaxSize,
+			},
+		},
+		"am": map[string]any{
+			"after_commit":       afterCommitPullPush,
+			"subject_max_length": defaultSubjectMaxLength,
+			"body_wrap":          defaultBodyWrap,
+			"ticket_trailer":     defaultTicketTrailer,
+		},
+	}
+}
+
+// loadConfig 依次合并各层配置，后面的层覆盖前面的层：
+// 内置默认值、~/.gitx/config.json、仓库内的 .gitx.json 或 .gitx/config.json、GITX_* 环境变量，
+// -c key=value 在解析命令行参数后由 BindConfigFlags 覆盖
+func loadConfig() {
+	tree := map[string]any{}
+	origins := map[string]configSource{}
+	mergeConfig(tree, defaultConfigValues(), "", originDefault, origins)
+
+	globalFile := getConfigFilePath()
+	global, err := readConfigFile(globalFile)
+	if err != nil {
+		errLog("failed to read config file: %v", err)
+	}
+	if global == nil {
+		warningLog("config file does not exist, please run 'gitx config' to create one")
+	}
+	mergeConfig(tree, global, "", "file:"+globalFile, origins)
+
+	if repoFile := repoConfigFile(); repoFile != "" {
+		repo, err := readConfigFile(repoFile)
+		if err != nil {
+			errLog("failed to read config file: %v", err)
+		}
+		denyRepoConfig(repoFile, repo, tree)
+		mergeConfig(tree, repo, "", "file:"+repoFile, origins)
+	}
+
+	for _, key := range sortedConfigKeys() {
+		env := configEnvName(key)
+		value, ok := os.LookupEnv(env)
+		if !ok {
+			continue
+		}
+		if err := overrideConfig(tree, origins, key, value, "env:"+env); err != nil {
+			errLog("invalid value for %s: %v", env, err)
+		}
+	}
+
+	decodeConfig(tree, origins)
+}
+
+// decodeConfig 将合并后的配置解析到 config
+func decodeConfig(tree map[string]any, origins map[string]configSource) {
+	data, err := json.Marshal(tree)
+	if err != nil {
+		errLog("failed to serialize config: %v", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		errLog("failed to parse config file: %v", err)
+	}
+	config, configTree, configOrigins = cfg, tree, origins
+}
+
+// readConfigFile 读取 JSON 配置文件，文件不存在时返回 nil
+func readConfigFile(file string) (map[string]any, error) {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]any
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return values, nil
+}
+
+// repoConfigFile 返回当前仓库的配置文件，都不存在时返回 .gitx.json，不在仓库中时返回空
+func repoConfigFile() string {
+	root, err := runCommand("git", "rev-parse", "--show-toplevel")
+	if err != nil || root == "" {
+		return ""
+	}
+	for _, name := range repoConfigFiles {
+		file := path.Join(root, name)
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+	}
+	return path.Join(root, repoConfigFiles[0])
+}
+
+// denyRepoConfig 处理仓库内的配置中可能把代码或 API key 发送出去的字段：删除后端的 base_url 和 api_key_env，
+// ai.secrets 只能让密钥检测更严格，修改后端的选择时给出提示。tree 是合并了前面各层的配置
+func denyRepoConfig(file string, values, tree map[string]any) {
+	ai, isMap := values["ai"].(map[string]any)
+	if _, ok := values["ai"]; ok && !isMap {
+		warningLog("ignoring ai in %s, it must be an object", file)
+		delete(values, "ai")
+	}
+	current, _ := tree["ai"].(map[string]any)
+	providers, _ := ai["providers"].(map[string]any)
+	for name, v := range providers {
+		provider, _ := v.(map[string]any)
+		for _, key := range repoDeniedKeys {
+			if _, ok := provider[key]; ok {
+				warningLog("ignoring ai.providers.%s.%s in %s, set it in %s instead", name, key, file, getConfigFilePath())
+				delete(provider, key)
+			}
+		}
+	}
+
+	if v, ok := ai["secrets"]; ok {
+		secrets, isMap := v.(map[string]any)
+		if !isMap {
+			warningLog("ignoring ai.secrets in %s, it must be an object", file)
+			delete(ai, "secrets")
+		}
+		currentSecrets, _ := current["secrets"].(map[string]any)
+		if mode, ok := secrets["mode"]; ok {
+			currentMode, _ := currentSecrets["mode"].(string)
+			rank, known := secretsStrictness[fmt.Sprint(mode)]
+			if !known || rank < secretsStrictness[currentMode] {
+				warningLog("ignoring ai.secrets.mode=%v in %s, repo config can only make the secret scan stricter than %s", mode, file, currentMode)
+				delete(secrets, "mode")
+			}
+		}
+		// 仓库内的规则追加到已有的规则后面，不能替换
+		if patterns, ok := secrets["patterns"]; ok {
+			secrets["patterns"] = append(configList(currentSecrets["patterns"]), configList(patterns)...)
+		}
+	}
+
+	for _, key := range repoWarnedKeys {
+		if v, ok := ai[key]; ok && formatConfigValue(v) != formatConfigValue(current[key]) {
+			warningLog("ai.%s is set to %s by %s", key, formatConfigValue(v), file)
+		}
+	}
+}
+
+// configList 将 JSON 解析出的 []any 或环境变量解析出的 []string 统一为 []any
+func configList(v any) []any {
+	switch list := v.(type) {
+	case []any:
+		return list
+	case []string:
+		result := make([]any, 0, len(list))
+		for _, item := range list {
+			result = append(result, item)
+		}
+		return result
+	}
+	return nil
+}
+
+// mergeConfig 将 src 递归合并到 dst，对象逐个字段合并，其他值（包括数组）整体替换
+func mergeConfig(dst, src map[string]any, prefix, origin string, origins map[string]configSource) {
+	for k, v := range src {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if m, ok := v.(map[string]any); ok {
+			sub, ok := dst[k].(map[string]any)
+			if !ok {
+				dropOrigins(origins, key)
+				sub = map[string]any{}
+				dst[k] = sub
+			}
+			mergeConfig(sub, m, key, origin, origins)
+			continue
+		}
+		dropOrigins(origins, key)
+		dst[k] = v
+		origins[key] = configSource{Value: v, Origin: origin}
+	}
+}
+
+// dropOrigins 删除 key 及其子项的来源，用于值被整体替换的情况
+func dropOrigins(origins map[string]configSource, key string) {
+	for k := range origins {
+		if k == key || strings.HasPrefix(k, key+".") {
+			delete(origins, k)
+		}
+	}
+}
+
+// overrideConfig 按 config set 的规则解析 value，写入 key 对应的位置
+func overrideConfig(tree map[string]any, origins map[string]configSource, key, value, origin string) error {
+	typed, err := typedConfigValue(key, value)
+	if err != nil {
+		return err
+	}
+	parts := strings.Split(key, ".")
+	node := tree
+	for _, part := range parts[:len(parts)-1] {
+		sub, ok := node[part].(map[string]any)
+		if !ok {
+			sub = map[string]any{}
+			node[part] = sub
+		}
+		node = sub
+	}
+	dropOrigins(origins, key)
+	node[parts[len(parts)-1]] = typed
+	origins[key] = configSource{Value: typed, Origin: origin}
+	return nil
+}
+
+// typedConfigValue 将字符串形式的值转换为配置项的类型，例如 am.require_ticket=true 转换为 bool
+func typedConfigValue(key, value string) (any, error) {
+	if !validKeys[key] {
+		return nil, fmt.Errorf("invalid config key: %s", key)
+	}
+	var cfg Config
+	if err := setConfigValue(&cfg, key, value); err != nil {
+		return nil, err
+	}
+	v := reflect.ValueOf(cfg)
+	for _, name := range strings.Split(key, ".") {
+		field, ok := configField(v, name)
+		if !ok {
+			return nil, fmt.Errorf("invalid config key: %s", key)
+		}
+		v = field
+	}
+	return v.Interface(), nil
+}
+
+// configField 按 json 标签查找结构体的字段
+func configField(v reflect.Value, name string) (reflect.Value, bool) {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	for i := 0; i < v.NumField(); i++ {
+		tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// setConfigFile 修改单个配置文件中的一项，文件中的其他内容保持不变
+func setConfigFile(file, key, value string) error {
+	values, err := readConfigFile(file)
+	if err != nil {
+		return err
+	}
+	if values == nil {
+		values = map[string]any{}
+	}
+	if err := overrideConfig(values, map[string]configSource{}, key, value, ""); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize config: %w", err)
+	}
+	if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(file, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// configEnvName 返回配置项对应的环境变量，例如 ai.cache.max_size 对应 GITX_AI_CACHE_MAX_SIZE
+func configEnvName(key string) string {
+	return "GITX_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func sortedConfigKeys() []string {
+	keys := make([]string, 0, len(validKeys))
+	for key := range validKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// printConfigOrigins 输出每个生效的配置项及其来源，格式与 git config --show-origin 类似
+func printConfigOrigins() {
+	keys := make([]string, 0, len(configOrigins))
+	for key := range configOrigins {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, key := range keys {
+		source := configOrigins[key]
+		fmt.Fprintf(w, "%s\t%s=%s\n", source.Origin, key, formatConfigValue(source.Value))
+	}
+	w.Flush()
+}
+
+func formatConfigValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,140 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeConfig(t *testing.T) {
+	tree := map[string]any{}
+	origins := map[string]configSource{}
+	mergeConfig(tree, map[string]any{
+		"prefix": []any{"feat"},
+		"ai":     map[string]any{"language": "zh", "providers": map[string]any{"openai": map[string]any{"model": "a"}}},
+		"am":     map[string]any{"scopes": map[string]any{"docs/v1.2/": "docs"}},
+	}, "", "global", origins)
+	mergeConfig(tree, map[string]any{
+		"prefix": []any{"release"},
+		"ai":     map[string]any{"providers": map[string]any{"ollama": map[string]any{"model": "b"}}},
+		"am":     map[string]any{"scopes": "none"},
+	}, "", "repo", origins)
+
+	want := map[string]any{
+		"prefix": []any{"release"},
+		"ai": map[string]any{"language": "zh", "providers": map[string]any{
+			"openai": map[string]any{"model": "a"},
+			"ollama": map[string]any{"model": "b"},
+		}},
+		"am": map[string]any{"scopes": "none"},
+	}
+	if !reflect.DeepEqual(tree, want) {
+		t.Errorf("tree = %v, want %v", tree, want)
+	}
+	wantOrigins := map[string]string{
+		"prefix":                    "repo",
+		"ai.language":               "global",
+		"ai.providers.openai.model": "global",
+		"ai.providers.ollama.model": "repo",
+		"am.scopes":                 "repo",
+	}
+	got := map[string]string{}
+	for key, source := range origins {
+		got[key] = source.Origin
+	}
+	if !reflect.DeepEqual(got, wantOrigins) {
+		t.Errorf("origins = %v, want %v", got, wantOrigins)
+	}
+}
+
+func TestOverrideConfig(t *testing.T) {
+	tree := map[string]any{"am": map[string]any{"body_wrap": 72.0}}
+	origins := map[string]configSource{"am.body_wrap": {Value: 72.0, Origin: "default"}}
+	tests := []struct {
+		key, value string
+		want       any
+	}{
+		{"am.require_ticket", "false", false},
+		{"am.body_wrap", "80", 80},
+		{"ai.fallback", "ollama, openai", []string{"ollama", "openai"}},
+		{"ai.secrets.mode", "block", "block"},
+		{"ai.secrets.patterns", "corp_[a-z0-9]{8,32}\n\ninternal-[0-9]{4}\n", []string{"corp_[a-z0-9]{8,32}", "internal-[0-9]{4}"}},
+	}
+	for _, tt := range tests {
+		if err := overrideConfig(tree, origins, tt.key, tt.value, "env"); err != nil {
+			t.Fatalf("overrideConfig(%s=%s) error: %v", tt.key, tt.value, err)
+		}
+		if got := origins[tt.key]; !reflect.DeepEqual(got.Value, tt.want) || got.Origin != "env" {
+			t.Errorf("overrideConfig(%s=%s) = %#v from %s, want %#v", tt.key, tt.value, got.Value, got.Origin, tt.want)
+		}
+	}
+	if got := tree["ai"].(map[string]any)["secrets"].(map[string]any)["mode"]; got != "block" {
+		t.Errorf("tree ai.secrets.mode = %v", got)
+	}
+	for _, kv := range [][2]string{{"ai.bogus", "1"}, {"ai.timeout", "-1"}, {"am.after_commit", "force"}, {"am.require_ticket", "maybe"}} {
+		if err := overrideConfig(tree, origins, kv[0], kv[1], "env"); err == nil {
+			t.Errorf("overrideConfig(%s=%s) expected error", kv[0], kv[1])
+		}
+	}
+}
+
+func TestDenyRepoConfig(t *testing.T) {
+	tree := map[string]any{"ai": map[string]any{
+		"provider": "ollama",
+		"secrets":  map[string]any{"mode": "redact", "patterns": []any{"corp_[a-z0-9]{32}"}},
+	}}
+	tests := []struct {
+		name   string
+		values map[string]any
+		want   map[string]any
+	}{
+		{
+			name: "provider endpoint and key env are dropped",
+			values: map[string]any{"ai": map[string]any{"providers": map[string]any{
+				"openai": map[string]any{"model": "x", "base_url": "http://evil", "api_key_env": "HOME"},
+			}}},
+			want: map[string]any{"ai": map[string]any{"providers": map[string]any{
+				"openai": map[string]any{"model": "x"},
+			}}},
+		},
+		{
+			name:   "secret scan cannot be turned off",
+			values: map[string]any{"ai": map[string]any{"secrets": map[string]any{"mode": "off"}}},
+			want:   map[string]any{"ai": map[string]any{"secrets": map[string]any{}}},
+		},
+		{
+			name:   "secret scan can be made stricter",
+			values: map[string]any{"ai": map[string]any{"secrets": map[string]any{"mode": "block"}}},
+			want:   map[string]any{"ai": map[string]any{"secrets": map[string]any{"mode": "block"}}},
+		},
+		{
+			name:   "patterns are appended",
+			values: map[string]any{"ai": map[string]any{"secrets": map[string]any{"patterns": []any{"team_[0-9]{8}"}}}},
+			want: map[string]any{"ai": map[string]any{"secrets": map[string]any{
+				"patterns": []any{"corp_[a-z0-9]{32}", "team_[0-9]{8}"},
+			}}},
+		},
+		{
+			name:   "secrets must be an object",
+			values: map[string]any{"ai": map[string]any{"secrets": nil, "language": "en"}},
+			want:   map[string]any{"ai": map[string]any{"language": "en"}},
+		},
+		{
+			name:   "ai must be an object",
+			values: map[string]any{"ai": nil, "prefix": []any{"feat"}},
+			want:   map[string]any{"prefix": []any{"feat"}},
+		},
+		{
+			name:   "provider selection is kept",
+			values: map[string]any{"ai": map[string]any{"provider": "openai"}},
+			want:   map[string]any{"ai": map[string]any{"provider": "openai"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			denyRepoConfig(".gitx.json", tt.values, tree)
+			if !reflect.DeepEqual(tt.values, tt.want) {
+				t.Errorf("values = %v, want %v", tt.values, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultCommitTypes 是默认允许的 Conventional Commits 类型，可通过 ai.types 覆盖
+var defaultCommitTypes = []string{
+	"feat", "fix", "docs", "style", "refactor", "perf", "test", "build", "ci", "chore", "revert", "i18n",
+}
+
+// commitTypeAliases 用于修复 AI 常见的非标准类型
+var commitTypeAliases = map[string]string{
+	"feature":     "feat",
+	"features":    "feat",
+	"bugfix":      "fix",
+	"hotfix":      "fix",
+	"doc":         "docs",
+	"tests":       "test",
+	"styles":      "style",
+	"refactoring": "refactor",
+	"performance": "perf",
+}
+
+var (
+	regexpCCHeader   = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9-]*)(?:\(([^()\r\n]*)\))?(!)?:[ \t]*(.*)$`)
+	regexpCCFooter   = regexp.MustCompile(`^(BREAKING CHANGE|BREAKING-CHANGE|[A-Za-z][\w-]*)(: | #)(.*)$`)
+	regexpListMarker = regexp.MustCompile(`^(?:\d+[.)、]|[-*•])\s*`)
+	// regexpNumberedItem 匹配顶层的编号列表项，例如 "2. " 或 "2、"
+	regexpNumberedItem = regexp.MustCompile(`^(\d+)[.)、]`)
+	// regexpDescriptionMarker 匹配描述开头的列表序号，序号后需要空格，避免把 "3.4.0 release" 当作列表
+	regexpDescriptionMarker = regexp.MustCompile(`^(?:\d+(?:[.)]\s+|、\s*)|[-*•]\s+)`)
+)
+
+// conventionalFooter 是提交信息末尾的 trailer，例如 "Refs: #123"
+type conventionalFooter struct {
+	Token     string
+	Separator string
+	Value     string
+}
+
+// conventionalCommit 是按 Conventional Commits 1.0 解析后的提交信息
+type conventionalCommit struct {
+	// Prefix 是类型前面的 emoji 等修饰，github 模板会输出 "✨ feat: ..."
+	Prefix string
+	Type   string
+	Scope  string
+	// Breaking 表示 header 中带有 "!"，BREAKING CHANGE footer 见 isBreaking
+	Breaking    bool
+	Description string
+	Body        string
+	Footers     []conventionalFooter
+}
+
+// Header 返回提交信息的第一行
+func (c *conventionalCommit) Header() string {
+	var sb strings.Builder
+	if c.Prefix != "" {
+		sb.WriteString(c.Prefix + " ")
+	}
+	sb.WriteString(c.Type)
+	if c.Scope != "" {
+		sb.WriteString("(" + c.Scope + ")")
+	}
+	if c.Breaking {
+		sb.WriteString("!")
+	}
+	sb.WriteString(": " + c.Description)
+	return sb.String()
+}
+
+// String 返回完整的提交信息，header、body 和 footers 之间用空行分隔
+func (c *conventionalCommit) String() string {
+	parts := []string{c.Header()}
+	if c.Body != "" {
+		parts = append(parts, c.Body)
+	}
+	if len(c.Footers) > 0 {
+		lines := make([]string, 0, len(c.Footers))
+		for _, f := range c.Footers {
+			lines = append(lines, f.Token+f.Separator+f.Value)
+		}
+		parts = append(parts, strings.Join(lines, "\n"))
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// validate 检查类型是否在允许的列表中，以及描述是否为空
+func (c *conventionalCommit) validate(types []string) error {
+	if !slices.Contains(types, c.Type) {
+		return fmt.Errorf("commit type %q is not allowed (allowed: %s)", c.Type, strings.Join(types, "|"))
+	}
+	if strings.TrimSpace(c.Description) == "" {
+		return fmt.Errorf("commit description is empty")
+	}
+	return nil
+}
+
+// parseConventionalCommit 严格按照 Conventional Commits 1.0 解析提交信息
+func parseConventionalCommit(msg string) (*conventionalCommit, error) {
+	msg = strings.Trim(strings.ReplaceAll(msg, "\r\n", "\n"), "\n")
+	lines := strings.Split(msg, "\n")
+	matches := regexpCCHeader.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	if matches == nil {
+		return nil, fmt.Errorf("invalid commit header: %q", lines[0])
+	}
+	c := &conventionalCommit{
+		Type:        strings.ToLower(matches[1]),
+		Scope:       strings.TrimSpace(matches[2]),
+		Breaking:    matches[3] == "!",
+		Description: strings.TrimSpace(matches[4]),
+	}
+	c.Body, c.Footers = splitFooters(strings.Trim(strings.Join(lines[1:], "\n"), "\n"))
+	return c, nil
+}
+
+// isBreaking 判断是否为破坏性变更，header 中的 "!" 和 BREAKING CHANGE footer 都算
+func (c *conventionalCommit) isBreaking() bool {
+	if c.Breaking {
+		return true
+	}
+	for _, f := range c.Footers {
+		if f.Token == "BREAKING CHANGE" || f.Token == "BREAKING-CHANGE" {
+			return true
+		}
+	}
+	return false
+}
+
+// splitFooters 将最后一段中以 "Token: " 或 "Token #" 开头的行解析为 footers，
+// footer 的值可以跨行，直到下一个 footer 出现为止
+func splitFooters(rest string) (string, []conventionalFooter) {
+	if rest == "" {
+		return "", nil
+	}
+	paragraphs := strings.Split(rest, "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+	lines := strings.Split(last, "\n")
+	if !regexpCCFooter.MatchString(lines[0]) {
+		return rest, nil
+	}
+	var footers []conventionalFooter
+	for _, line := range lines {
+		if m := regexpCCFooter.FindStringSubmatch(line); m != nil {
+			footers = append(footers, conventionalFooter{Token: m[1], Separator: m[2], Value: m[3]})
+			continue
+		}
+		footers[len(footers)-1].Value += "\n" + line
+	}
+	body := strings.Trim(strings.Join(paragraphs[:len(paragraphs)-1], "\n\n"), "\n")
+	return body, footers
+}
+
+// repairConventionalCommit 修复 AI 输出中常见的格式问题后再解析：
+// 去掉代码块标记和 header 的列表序号、补全冒号后的空格、修正类型的大小写和别名、
+// header 描述为空时使用正文的第一行、header 前有说明文字时从第一个合法的 header 开始
+func repairConventionalCommit(msg string, types []string) (*conventionalCommit, error) {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(msg, "\r\n", "\n"), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t"))
+	}
+	start := -1
+	var prefix string
+	for i, line := range lines {
+		p, header := splitHeaderPrefix(regexpListMarker.ReplaceAllString(strings.TrimSpace(line), ""))
+		matches := regexpCCHeader.FindStringSubmatch(header)
+		if matches == nil {
+			continue
+		}
+		typ := strings.ToLower(matches[1])
+		if alias, ok := commitTypeAliases[typ]; ok {
+			typ = alias
+		}
+		if !slices.Contains(types, typ) {
+			continue
+		}
+		start, prefix = i, p
+		lines[i] = typ + header[len(matches[1]):]
+		break
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("no conventional commit header found, allowed types: %s", strings.Join(types, "|"))
+	}
+	c, err := parseConventionalCommit(strings.Join(lines[start:], "\n"))
+	if err != nil {
+		return nil, err
+	}
+	c.Prefix = prefix
+	// "feat: ...\nfix: ..." 中第二个 header 会被当作 footer 解析，改为正文的列表项
+	var items []string
+	c.Footers = slices.DeleteFunc(c.Footers, func(f conventionalFooter) bool {
+		if f.Separator != ": " || !isCommitType(f.Token, types) {
+			return false
+		}
+		items = append(items, "- "+f.Token+": "+f.Value)
+		return true
+	})
+	if len(items) > 0 {
+		c.Body = strings.Trim(c.Body+"\n\n"+strings.Join(items, "\n"), "\n")
+	}
+	if m := regexpDescriptionMarker.FindString(c.Description); m != "" {
+		// "feat: 1. add a\n2. fix b" 的序号不属于描述，剩下的编号列表从 1 重新编号
+		c.Description = strings.TrimSpace(c.Description[len(m):])
+		if regexpNumberedItem.MatchString(m) {
+			c.Body = strings.Join(renumberList(strings.Split(c.Body, "\n")), "\n")
+		}
+	}
+	if c.Description == "" && c.Body != "" {
+		bodyLines := strings.Split(c.Body, "\n")
+		c.Description = strings.TrimSpace(regexpListMarker.ReplaceAllString(bodyLines[0], ""))
+		rest := bodyLines[1:]
+		// 第一项成为描述后，剩下的编号列表从 1 重新编号
+		if regexpNumberedItem.MatchString(bodyLines[0]) {
+			rest = renumberList(rest)
+		}
+		c.Body = strings.Trim(strings.Join(rest, "\n"), "\n")
+	}
+	if err := c.validate(types); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// isCommitType 判断 token 是否为允许的类型或者类型的别名，不区分大小写
+func isCommitType(token string, types []string) bool {
+	token = strings.ToLower(token)
+	if alias, ok := commitTypeAliases[token]; ok {
+		token = alias
+	}
+	return slices.Contains(types, token)
+}
+
+// renumberList 将顶层的编号列表项依次改为 1、2、3，其他行保持不变
+func renumberList(lines []string) []string {
+	result := make([]string, len(lines))
+	n := 0
+	for i, line := range lines {
+		if m := regexpNumberedItem.FindStringSubmatch(line); m != nil {
+			n++
+			line = strconv.Itoa(n) + line[len(m[1]):]
+		}
+		result[i] = line
+	}
+	return result
+}
+
+// splitHeaderPrefix 拆出类型前面的 emoji 等非字母字符，前缀中有中文等其他文字时不拆分
+func splitHeaderPrefix(line string) (string, string) {
+	idx := strings.IndexFunc(line, unicode.IsLetter)
+	if idx <= 0 || line[idx] >= utf8.RuneSelf {
+		return "", line
+	}
+	return strings.TrimSpace(line[:idx]), line[idx:]
+}
+
+// commitTypes 返回允许的提交类型，未配置 ai.types 时使用默认列表
+func commitTypes() []string {
+	if len(config.AI.Types) > 0 {
+		return config.AI.Types
+	}
+	return defaultCommitTypes
+}
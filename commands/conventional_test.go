@@ -0,0 +1,76 @@
+package commands
+
+import "testing"
+
+func TestParseConventionalCommitType(t *testing.T) {
+	tests := []struct {
+		msg, typ, scope, desc string
+	}{
+		{"feat: add users endpoint", "feat", "", "add users endpoint"},
+		{"fix(api)!: drop v1 routes", "fix", "api", "drop v1 routes"},
+		{"i18n: add zh translations", "i18n", "", "add zh translations"},
+		{"i18n(ui): translate settings page", "i18n", "ui", "translate settings page"},
+		{"ops-infra: rotate keys", "ops-infra", "", "rotate keys"},
+	}
+	for _, tt := range tests {
+		c, err := parseConventionalCommit(tt.msg)
+		if err != nil {
+			t.Errorf("parseConventionalCommit(%q) error: %v", tt.msg, err)
+			continue
+		}
+		if c.Type != tt.typ || c.Scope != tt.scope || c.Description != tt.desc {
+			t.Errorf("parseConventionalCommit(%q) = %q, %q, %q; want %q, %q, %q", tt.msg, c.Type, c.Scope, c.Description, tt.typ, tt.scope, tt.desc)
+		}
+	}
+	for _, msg := range []string{"1fix: bad", "-feat: bad", "update readme"} {
+		if _, err := parseConventionalCommit(msg); err == nil {
+			t.Errorf("parseConventionalCommit(%q) expected error", msg)
+		}
+	}
+}
+
+func TestRepairConventionalCommitTypes(t *testing.T) {
+	tests := []struct {
+		msg    string
+		types  []string
+		header string
+	}{
+		{"i18n: add zh translations", defaultCommitTypes, "i18n: add zh translations"},
+		{"🌐 i18n(ui): translate settings page", defaultCommitTypes, "🌐 i18n(ui): translate settings page"},
+		{"ops-infra: rotate keys", []string{"ops-infra", "feat"}, "ops-infra: rotate keys"},
+	}
+	for _, tt := range tests {
+		c, err := repairConventionalCommit(tt.msg, tt.types)
+		if err != nil {
+			t.Errorf("repairConventionalCommit(%q) error: %v", tt.msg, err)
+			continue
+		}
+		if got := c.Header(); got != tt.header {
+			t.Errorf("repairConventionalCommit(%q) header = %q, want %q", tt.msg, got, tt.header)
+		}
+	}
+}
+
+func TestRepairConventionalCommitList(t *testing.T) {
+	tests := []struct {
+		msg, want string
+	}{
+		{"feat:\n1. add a\n2. fix b\n3. drop c", "feat: add a\n\n1. fix b\n2. drop c"},
+		{"feat:\n- add a\n- fix b", "feat: add a\n\n- fix b"},
+		{"feat: 1. add a\n2. fix b\n3. drop c", "feat: add a\n\n1. fix b\n2. drop c"},
+		{"feat: 1、添加功能\n2、修复问题", "feat: 添加功能\n\n1、修复问题"},
+		{"feat: 3.4.0 release", "feat: 3.4.0 release"},
+		{"feat: 添加功能\nfix: 修复问题", "feat: 添加功能\n\n- fix: 修复问题"},
+		{"feat: add a\n\nbody\n\nBugfix: handle nil\nRefs: #12", "feat: add a\n\nbody\n\n- Bugfix: handle nil\n\nRefs: #12"},
+	}
+	for _, tt := range tests {
+		c, err := repairConventionalCommit(tt.msg, defaultCommitTypes)
+		if err != nil {
+			t.Errorf("repairConventionalCommit(%q) error: %v", tt.msg, err)
+			continue
+		}
+		if got := c.String(); got != tt.want {
+			t.Errorf("repairConventionalCommit(%q) = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+}
@@ -18,6 +18,7 @@ var FetchCmd = &cobra.Command{
 	Use:   "fetch",
 	Short: "Merge main branch into current feat branch, like merge main into feat-3.4.0",
 	Run: func(cmd *cobra.Command, args []string) {
+		resolveMainBranch(cmd, &mainBranch)
 		pwd, err := os.Getwd()
 		if err != nil {
 			errLog("failed to get current working directory: %v", err)
@@ -36,7 +37,7 @@ var FetchCmd = &cobra.Command{
 		execCommand("git", "pull", "origin", mainBranch)
 		execCommand("git", "checkout", version)
 		execCommand("git", "pull", "origin", version)
-		execCommand("git", "merge", "--no-ff", "-m",
+		mergeBranch(cmd, "--no-ff", "-m",
 			fmt.Sprintf("[Branch Merge] Merge %s into %s", mainBranch, version), mainBranch)
 		execCommand("git", "push", "--set-upstream", "origin", version)
 		successLog("Fetched updates and merged [%s] into [%s]", mainBranch, version)
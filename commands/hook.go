@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const hookMarker = "# installed by gitx hook install"
+
+var (
+	hookPrompt string
+	hookForce  bool
+)
+
+func init() {
+	hookInstallCmd.Flags().StringVarP(&hookPrompt, "prompt", "p", "default", "Set the prompt template used by the hook")
+	hookInstallCmd.Flags().BoolVarP(&hookForce, "force", "f", false, "Overwrite an existing prepare-commit-msg hook")
+	hookRunCmd.Flags().StringVarP(&hookPrompt, "prompt", "p", "default", "Set the prompt template")
+	HookCmd.AddCommand(hookInstallCmd, hookUninstallCmd, hookRunCmd)
+}
+
+var HookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Manage the prepare-commit-msg hook that fills in AI commit messages",
+}
+
+var hookInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install a prepare-commit-msg hook in the current repository",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := findPrompt(hookPrompt); err != nil {
+			errLog("%v", err)
+		}
+		file, err := hookPath()
+		if err != nil {
+			errLog("%v", err)
+		}
+		if data, err := os.ReadFile(file); err == nil && !strings.Contains(string(data), hookMarker) && !hookForce {
+			errLog("%s already exists and was not installed by gitx, use --force to overwrite it", file)
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			exe = "gitx"
+		}
+		// IDE 中 PATH 可能不包含 gitx，因此写入绝对路径
+		script := fmt.Sprintf("#!/bin/sh\n%s\nexec %q hook run --prompt %q \"$@\"\n", hookMarker, exe, hookPrompt)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			errLog("failed to create hooks directory: %v", err)
+		}
+		if err := os.WriteFile(file, []byte(script), 0755); err != nil {
+			errLog("failed to write hook: %v", err)
+		}
+		successLog("Installed prepare-commit-msg hook: %s", file)
+	},
+}
+
+var hookUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the prepare-commit-msg hook installed by gitx",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, err := hookPath()
+		if err != nil {
+			errLog("%v", err)
+		}
+		data, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			warningLog("No prepare-commit-msg hook installed.")
+			return
+		}
+		if err != nil {
+			errLog("failed to read hook: %v", err)
+		}
+		if !strings.Contains(string(data), hookMarker) {
+			errLog("%s was not installed by gitx, leaving it in place", file)
+		}
+		if err := os.Remove(file); err != nil {
+			errLog("failed to remove hook: %v", err)
+		}
+		successLog("Removed prepare-commit-msg hook: %s", file)
+	},
+}
+
+// hookRunCmd 由 prepare-commit-msg 调用：prepare-commit-msg <file> [source [sha]]
+var hookRunCmd = &cobra.Command{
+	Use:    "run <message-file> [source [sha]]",
+	Short:  "Fill in the commit message file, called by the prepare-commit-msg hook",
+	Hidden: true,
+	Args:   cobra.RangeArgs(1, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		source := ""
+		if len(args) > 1 {
+			source = args[1]
+		}
+		// -m/-F 已经给出了提交信息，commit.template、merge、squash 和 --amend/-c/-C 使用 git 准备好的信息
+		if source != "" {
+			return
+		}
+		if err := fillCommitMessage(cmd, args[0]); err != nil {
+			var ticketErr *missingTicketError
+			if errors.As(err, &ticketErr) {
+				errLog("%v", err)
+			}
+			// 生成失败时不影响提交，由用户自己填写
+			warningLog("gitx: %v", err)
+		}
+	},
+}
+
+// missingTicketError 表示开启了 am.require_ticket 但没有找到 issue 编号，此时 hook 会拒绝提交
+type missingTicketError struct {
+	err error
+}
+
+func (e *missingTicketError) Error() string {
+	return e.err.Error()
+}
+
+// hookPath 返回 prepare-commit-msg 的路径，支持 core.hooksPath
+func hookPath() (string, error) {
+	hooks, err := runCommand("git", "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", fmt.Errorf("not a git repository: %w", err)
+	}
+	return filepath.Abs(filepath.Join(hooks, "prepare-commit-msg"))
+}
+
+// fillCommitMessage 生成提交信息并写在消息文件的开头，保留 git 写入的模板和注释
+func fillCommitMessage(cmd *cobra.Command, file string) error {
+	// hook 中没有终端，不显示菜单也不流式输出
+	aiConfirm = true
+	noStream = true
+	diff, err := runCommand("git", stagedDiffArgs()...)
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		return nil
+	}
+	tmpl, err := findPrompt(hookPrompt)
+	if err != nil {
+		return err
+	}
+	data := collectPromptData(languageName(commitLanguage(cmd)))
+	tickets, err := commitTickets(&data)
+	if err != nil {
+		if config.AM.RequireTicket {
+			return &missingTicketError{err: err}
+		}
+		return err
+	}
+	message, err := composeCommitMessage(newAIGenerator(cmd), tmpl, hookPrompt, data, tickets, diff, false)
+	if err != nil {
+		return err
+	}
+	existing, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append([]byte(message), existing...), 0644)
+}
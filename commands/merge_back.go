@@ -17,7 +17,7 @@ var MergeBackCmd = &cobra.Command{
 		// git pull
 		execCommand("git", "pull", "origin", targetBranch)
 		// git merge --no-ff currentBranch
-		execCommand("git", "merge", "--no-ff", currentBranch)
+		mergeBranch(cmd, "--no-ff", currentBranch)
 		successLog("Merged branch %s back to %s successfully.", currentBranch, targetBranch)
 		// git push
 		execCommand("git", "push", "origin", targetBranch)
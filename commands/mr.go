@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+//go:embed prompts/mr.prompt
+var mrPrompt string
+
+var (
+	mrMainBranch string
+	mrJSON       bool
+)
+
+var regexpMRTitle = regexp.MustCompile(`(?i)^(?:#+\s*)?(?:\*\*)?title(?:\*\*)?\s*[:：]\s*`)
+
+func init() {
+	mrDescribeCmd.Flags().StringVarP(&mrMainBranch, "branch", "b", "main", "Main branch name, default is 'main'")
+	mrDescribeCmd.Flags().BoolVarP(&mrJSON, "json", "", false, "Print the title and description as JSON")
+	bindAIFlags(mrDescribeCmd, "description")
+	MRCmd.AddCommand(mrDescribeCmd)
+}
+
+var MRCmd = &cobra.Command{
+	Use:   "mr",
+	Short: "Work with merge requests of the current feat branch",
+}
+
+// mergeRequest 是生成的合并请求标题和描述，--json 时原样输出，方便对接各种代码托管平台
+type mergeRequest struct {
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+}
+
+var mrDescribeCmd = &cobra.Command{
+	Use:   "describe [feat-branch]",
+	Short: "Generate a merge request title and description from the commits of the feat branch",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		resolveMainBranch(cmd, &mrMainBranch)
+		feature := ""
+		if len(args) > 0 {
+			feature = args[0]
+		} else {
+			feature = featureBranch()
+		}
+		source, target := rangeRef(feature), rangeRef(mrMainBranch)
+		commits, err := runCommand("git", "log", "--no-merges", "--format=- %h %s%n%w(0,2,2)%b", target+".."+source)
+		if err != nil {
+			errLog("git log fail: %v", err)
+		}
+		if strings.TrimSpace(commits) == "" {
+			errLog("No commits between %s and %s.", target, source)
+		}
+		stat, err := runCommand("git", "diff", "--stat", target+"..."+source)
+		if err != nil {
+			errLog("git diff --stat fail: %v", err)
+		}
+
+		sp, err := renderPrompt("builtin/mr", mrPrompt, promptData{Language: languageName(commitLanguage(cmd))})
+		if err != nil {
+			errLog("Render prompt fail: %v", err)
+		}
+		user := fmt.Sprintf("功能分支：%s\n目标分支：%s\n\n提交记录：\n%s\n\n改动统计：\n%s", feature, mrMainBranch, commits, stat)
+		gen := newAIGenerator(cmd)
+		// 输出 JSON 时不能混入流式输出
+		if mrJSON {
+			gen.stream = nil
+		}
+		if err := confirmTokens(gen, estimateRequests(sp, user), 1); err != nil {
+			errLog("%v", err)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		var mr mergeRequest
+		msg, agent, err := gen.generate(ctx, sp, user, func(msg string) error {
+			var err error
+			mr.Title, mr.Description, err = parseMergeRequest(msg)
+			return err
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				errLog("Canceled.")
+			}
+			errLog("Generate merge request description fail: %v", err)
+		}
+		mr.Title, mr.Description, _ = parseMergeRequest(msg)
+		mr.SourceBranch, mr.TargetBranch = feature, mrMainBranch
+		if mrJSON {
+			data, _ := json.MarshalIndent(mr, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+		successLog("Merge request description generated by [%s]", agent)
+		fmt.Printf("%s\n\n%s\n", mr.Title, mr.Description)
+	},
+}
+
+// featureBranch 从项目目录名中取出 feat 分支，例如 deliangyang-gitx-feat-3.4.0-new-dev 中的 feat-3.4.0，
+// 目录名不匹配时使用当前分支
+func featureBranch() string {
+	pwd, err := os.Getwd()
+	if err != nil {
+		errLog("failed to get current working directory: %v", err)
+	}
+	if matches := regexpProject.FindStringSubmatch(path.Base(pwd)); matches != nil {
+		if isDebug {
+			log.Printf("matches: [%s]\n", strings.Join(matches, ", "))
+		}
+		return matches[1]
+	}
+	branch, err := runCommand("git", "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		errLog("failed to get current branch: %v", err)
+	}
+	return branch
+}
+
+// rangeRef 优先使用本地分支，本地不存在时使用 origin 上的分支
+func rangeRef(branch string) string {
+	if _, err := runCommand("git", "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+		return branch
+	}
+	if _, err := runCommand("git", "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+branch); err == nil {
+		return "origin/" + branch
+	}
+	return branch
+}
+
+// parseMergeRequest 拆分标题和描述，标题是第一行非空内容，可以带 "Title:" 前缀
+func parseMergeRequest(msg string) (string, string, error) {
+	text := strings.Trim(stripCodeFence(msg), "\n ")
+	title, description, _ := strings.Cut(text, "\n")
+	title = strings.TrimSpace(regexpMRTitle.ReplaceAllString(strings.TrimSpace(title), ""))
+	title = strings.TrimSpace(strings.TrimPrefix(title, "# "))
+	if title == "" {
+		return "", "", errors.New("empty merge request title")
+	}
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return "", "", errors.New("empty merge request description")
+	}
+	return title, description, nil
+}
@@ -0,0 +1,311 @@
+package commands
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+//go:embed prompts/review.prompt
+var reviewPrompt string
+
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+var (
+	reviewSeverities = []string{severityError, severityWarning, severityInfo}
+	reviewFormats    = []string{"text", "json", "sarif"}
+)
+
+var (
+	reviewStaged bool
+	reviewBase   string
+	reviewFormat string
+	reviewFailOn string
+)
+
+func init() {
+	ReviewCmd.Flags().BoolVarP(&reviewStaged, "staged", "", false, "Review only the staged changes, by default unstaged changes are reviewed when nothing is staged")
+	ReviewCmd.Flags().StringVarP(&reviewBase, "branch", "b", "", "Review the changes of the current branch since it forked from <base>")
+	ReviewCmd.MarkFlagsMutuallyExclusive("staged", "branch")
+	ReviewCmd.Flags().StringVarP(&reviewFormat, "format", "f", "text", "Set the output format (text|json|sarif)")
+	ReviewCmd.Flags().StringVarP(&reviewFailOn, "fail-on", "", "", "Exit with status 1 when a finding has this severity or higher (error|warning|info)")
+	ReviewCmd.Flags().StringSliceVarP(&excludeFiles, "exclude", "e", []string{}, "Comma-separated list of pathspec globs excluded from the review, added to am.exclude")
+	ReviewCmd.Flags().IntVarP(&limitLength, "limit", "l", 10000, "Set the maximum length of git diff sent in one request, larger diffs are reviewed in chunks")
+	ReviewCmd.Flags().IntVarP(&maxChunks, "max-chunks", "", 20, "Set the maximum number of chunks when reviewing a large diff")
+	bindAIFlags(ReviewCmd, "findings")
+	ReviewCmd.Flags().StringVarP(&secretsFlag, "secrets", "", "", "Set how secrets found in the diff are handled before sending it to AI (redact|block|off), overrides ai.secrets.mode (default redact)")
+}
+
+// reviewFinding 是审查发现的一个问题
+type reviewFinding struct {
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	EndLine    int    `json:"end_line,omitempty"`
+	Severity   string `json:"severity"`
+	Rule       string `json:"rule,omitempty"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+type reviewResult struct {
+	Findings []reviewFinding `json:"findings"`
+}
+
+var ReviewCmd = &cobra.Command{
+	Use:   "review [--staged|--branch <base>]",
+	Short: "Review staged, working tree or branch changes with AI",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !slices.Contains(reviewFormats, reviewFormat) {
+			errLog("invalid format: %s (available: %s)", reviewFormat, strings.Join(reviewFormats, "|"))
+		}
+		if reviewFailOn != "" && !slices.Contains(reviewSeverities, reviewFailOn) {
+			errLog("invalid --fail-on: %s (available: %s)", reviewFailOn, strings.Join(reviewSeverities, "|"))
+		}
+		diff, err := reviewDiffContent()
+		if err != nil {
+			errLog("git diff fail: %v", err)
+		}
+		if diff == "" {
+			successLog("No changes to review.")
+			return
+		}
+		sp, err := renderPrompt("builtin/review", reviewPrompt, promptData{Language: languageName(commitLanguage(cmd))})
+		if err != nil {
+			errLog("Render prompt fail: %v", err)
+		}
+		gen := newAIGenerator(cmd)
+		// 结果是 JSON，不需要流式输出
+		gen.stream = nil
+		findings, err := reviewDiff(gen, sp, diff)
+		if err != nil {
+			errLog("Review fail: %v", err)
+		}
+		switch reviewFormat {
+		case "json":
+			printJSON(reviewResult{Findings: findings})
+		case "sarif":
+			printJSON(sarifReport(findings))
+		default:
+			printFindings(findings)
+		}
+		if reviewFailOn != "" && slices.ContainsFunc(findings, func(f reviewFinding) bool {
+			return severityRank(f.Severity) <= severityRank(reviewFailOn)
+		}) {
+			os.Exit(1)
+		}
+	},
+}
+
+// reviewDiffContent 返回需要审查的 diff：--branch 时是分支分叉以来的改动，--staged 时只有暂存区，
+// 否则优先审查暂存区，暂存区为空时审查工作区中未暂存的改动
+func reviewDiffContent() (string, error) {
+	if reviewBase != "" {
+		return runCommand("git", append([]string{"diff", rangeRef(reviewBase) + "...HEAD"}, excludeArgs()...)...)
+	}
+	diff, err := runCommand("git", stagedDiffArgs()...)
+	if err != nil || diff != "" || reviewStaged {
+		return diff, err
+	}
+	return runCommand("git", append([]string{"diff"}, excludeArgs()...)...)
+}
+
+// reviewDiff 复用 am 的 diff 处理：排除、折叠、密钥检查和按 --limit 分段，每一段单独审查后合并结果
+func reviewDiff(gen *aiGenerator, system, diff string) ([]reviewFinding, error) {
+	files, _, err := prepareDiff(gen, diff)
+	if err != nil {
+		return nil, err
+	}
+	numberDiffLines(files)
+	chunks := chunkDiff(files, limitLength)
+	if len(chunks) > maxChunks {
+		return nil, fmt.Errorf("diff is too large (%d chunks of %d characters, max %d), narrow it with --exclude or raise --max-chunks", len(chunks), limitLength, maxChunks)
+	}
+	users := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		users[i] = "以下是 git diff 内容：\n" + chunk
+	}
+	if err := confirmTokens(gen, estimateRequests(system, users...), len(users)); err != nil {
+		return nil, err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var findings []reviewFinding
+	for i, user := range users {
+		if len(chunks) > 1 {
+			successLog("Reviewing chunk %d/%d", i+1, len(chunks))
+		}
+		msg, agent, err := gen.generate(ctx, system, user, func(msg string) error {
+			_, err := parseReviewFindings(msg)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
+		}
+		result, _ := parseReviewFindings(msg)
+		successLog("Reviewed by [%s], %d findings", agent, len(result))
+		findings = append(findings, result...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].File != findings[j].File {
+			return findings[i].File < findings[j].File
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings, nil
+}
+
+// numberDiffLines 在新增和未改动的行前标注新文件中的行号，便于模型给出准确的位置
+func numberDiffLines(files []fileDiff) {
+	for fi := range files {
+		for hi, hunk := range files[fi].Hunks {
+			lines := strings.Split(hunk, "\n")
+			newLine := 0
+			for li, line := range lines {
+				if m := regexpHunkHeader.FindStringSubmatch(line); m != nil {
+					newLine, _ = strconv.Atoi(m[2])
+					continue
+				}
+				if line == "" || (line[0] != '+' && line[0] != ' ') {
+					continue
+				}
+				lines[li] = fmt.Sprintf("%c%4d| %s", line[0], newLine, line[1:])
+				newLine++
+			}
+			files[fi].Hunks[hi] = strings.Join(lines, "\n")
+		}
+	}
+}
+
+// parseReviewFindings 解析模型返回的 JSON，容忍代码块标记和前后的说明文字
+func parseReviewFindings(msg string) ([]reviewFinding, error) {
+	start, end := strings.Index(msg, "{"), strings.LastIndex(msg, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object found in the response")
+	}
+	var result reviewResult
+	if err := json.Unmarshal([]byte(msg[start:end+1]), &result); err != nil {
+		return nil, fmt.Errorf("invalid review JSON: %w", err)
+	}
+	findings := result.Findings[:0]
+	for _, f := range result.Findings {
+		if f.File == "" || f.Message == "" {
+			continue
+		}
+		f.Severity = strings.ToLower(f.Severity)
+		if !slices.Contains(reviewSeverities, f.Severity) {
+			f.Severity = severityWarning
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+func severityRank(severity string) int {
+	return slices.Index(reviewSeverities, severity)
+}
+
+// printFindings 按文件分组输出问题
+func printFindings(findings []reviewFinding) {
+	if len(findings) == 0 {
+		successLog("No issues found.")
+		return
+	}
+	file := ""
+	for _, f := range findings {
+		if f.File != file {
+			if file != "" {
+				fmt.Println()
+			}
+			file = f.File
+			fmt.Println(file)
+		}
+		loc := strconv.Itoa(f.Line)
+		if f.EndLine > f.Line {
+			loc += "-" + strconv.Itoa(f.EndLine)
+		}
+		rule := ""
+		if f.Rule != "" {
+			rule = " [" + f.Rule + "]"
+		}
+		fmt.Printf("  %-9s %-7s %s%s\n", loc, f.Severity, f.Message, rule)
+		if f.Suggestion != "" {
+			fmt.Printf("  %-9s %-7s → %s\n", "", "", f.Suggestion)
+		}
+	}
+	counts := map[string]int{}
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	fmt.Printf("\n%d errors, %d warnings, %d info\n", counts[severityError], counts[severityWarning], counts[severityInfo])
+}
+
+func printJSON(v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		errLog("failed to serialize output: %v", err)
+	}
+	fmt.Println(string(data))
+}
+
+// sarifReport 生成 SARIF 2.1.0 报告，编辑器和代码扫描平台可以直接导入
+func sarifReport(findings []reviewFinding) map[string]any {
+	levels := map[string]string{severityError: "error", severityWarning: "warning", severityInfo: "note"}
+	var rules []map[string]any
+	seen := map[string]bool{}
+	results := make([]map[string]any, 0, len(findings))
+	for _, f := range findings {
+		ruleID := f.Rule
+		if ruleID == "" {
+			ruleID = "gitx-review"
+		}
+		if !seen[ruleID] {
+			seen[ruleID] = true
+			rules = append(rules, map[string]any{"id": ruleID})
+		}
+		region := map[string]any{"startLine": max(f.Line, 1)}
+		if f.EndLine > f.Line {
+			region["endLine"] = f.EndLine
+		}
+		text := f.Message
+		if f.Suggestion != "" {
+			text += "\n" + f.Suggestion
+		}
+		results = append(results, map[string]any{
+			"ruleId":  ruleID,
+			"level":   levels[f.Severity],
+			"message": map[string]any{"text": text},
+			"locations": []map[string]any{{
+				"physicalLocation": map[string]any{
+					"artifactLocation": map[string]any{"uri": f.File, "uriBaseId": "%SRCROOT%"},
+					"region":           region,
+				},
+			}},
+		})
+	}
+	return map[string]any{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []map[string]any{{
+			"tool": map[string]any{"driver": map[string]any{
+				"name":           "gitx-review",
+				"informationUri": "https://github.com/deliangyang/gitx",
+				"rules":          rules,
+			}},
+			"results": results,
+		}},
+	}
+}
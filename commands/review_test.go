@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestReviewDiffContent(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("GIT_AUTHOR_NAME", "gitx")
+	t.Setenv("GIT_AUTHOR_EMAIL", "gitx@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "gitx")
+	t.Setenv("GIT_COMMITTER_EMAIL", "gitx@example.com")
+	git := func(args ...string) {
+		t.Helper()
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(file, content string) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("init", "-q")
+	write("a.go", "package a\n")
+	write("b.go", "package b\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	write("a.go", "package a\n\n// staged\n")
+	git("add", "a.go")
+	write("b.go", "package b\n\n// unstaged\n")
+	t.Cleanup(func() { reviewStaged = false })
+
+	tests := []struct {
+		name   string
+		staged bool
+		want   string
+		skip   string
+	}{
+		{"staged first", false, "// staged", "// unstaged"},
+		{"staged only", true, "// staged", "// unstaged"},
+	}
+	for _, tt := range tests {
+		reviewStaged = tt.staged
+		diff, err := reviewDiffContent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(diff, tt.want) || strings.Contains(diff, tt.skip) {
+			t.Errorf("%s: diff = %q", tt.name, diff)
+		}
+	}
+
+	// 暂存区为空时审查工作区，--staged 时没有内容
+	git("commit", "-q", "-m", "staged")
+	reviewStaged = false
+	if diff, _ := reviewDiffContent(); !strings.Contains(diff, "// unstaged") {
+		t.Errorf("working tree diff = %q", diff)
+	}
+	reviewStaged = true
+	if diff, _ := reviewDiffContent(); diff != "" {
+		t.Errorf("--staged diff = %q, want empty", diff)
+	}
+}
+
+func TestBindAIFlags(t *testing.T) {
+	for _, cmd := range []*cobra.Command{AICommitCmd, ReviewCmd, ChangelogCmd, mrDescribeCmd} {
+		for _, name := range []string{"agent", "model", "lang", "timeout", "no-cache"} {
+			if cmd.Flags().Lookup(name) == nil {
+				t.Errorf("%s has no --%s", cmd.Name(), name)
+			}
+		}
+	}
+}
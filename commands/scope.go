@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"strings"
+)
+
+// inferScope 根据暂存的文件推断 Conventional Commits 的 scope：
+// 每个文件优先使用 am.scopes 中最长匹配的路径前缀，没有匹配时使用文件所在的顶层目录，
+// 所有文件得到相同的 scope 时返回该 scope，否则返回空
+func inferScope(files []string, scopes map[string]string) string {
+	var scope string
+	for _, file := range files {
+		s := fileScope(file, scopes)
+		if s == "" || (scope != "" && s != scope) {
+			return ""
+		}
+		scope = s
+	}
+	return scope
+}
+
+func fileScope(file string, scopes map[string]string) string {
+	var matched, scope string
+	for prefix, s := range scopes {
+		if strings.HasPrefix(file, prefix) && len(prefix) > len(matched) {
+			matched, scope = prefix, s
+		}
+	}
+	if matched != "" {
+		return scope
+	}
+	if idx := strings.Index(file, "/"); idx > 0 {
+		return file[:idx]
+	}
+	return ""
+}
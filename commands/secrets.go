@@ -0,0 +1,252 @@
+package commands
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	secretsRedact = "redact"
+	secretsBlock  = "block"
+	secretsOff    = "off"
+)
+
+// secretsModes 是 ai.secrets.mode 允许的取值，默认 redact
+var secretsModes = []string{secretsRedact, secretsBlock, secretsOff}
+
+// secretRule 是一条密钥检测规则
+type secretRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// defaultSecretRules 是内置的密钥检测规则，ai.secrets.patterns 中的规则会追加在后面
+var defaultSecretRules = []secretRule{
+	{"private-key", regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)},
+	{"aws-access-key-id", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"aws-secret-access-key", regexp.MustCompile(`(?i)aws.{0,20}(?:secret|private).{0,20}[=:]\s*["']?[A-Za-z0-9/+]{40}\b`)},
+	{"gcp-service-account", regexp.MustCompile(`"private_key_id"\s*:\s*"[0-9a-f]{40}"`)},
+	{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"gitlab-token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}\b`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abprs]-[0-9A-Za-z-]{10,}\b`)},
+	{"openai-api-key", regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_-]{20,}\b`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+	{"credential-url", regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^\s:/@]+:[^\s:/@]+@`)},
+}
+
+var (
+	regexpSecretToken = regexp.MustCompile(`[A-Za-z0-9+/_-]{24,}={0,2}`)
+	regexpEnvLine     = regexp.MustCompile(`^(\s*(?:export\s+)?[A-Za-z_][A-Za-z0-9_.]*\s*=\s*)(.+)$`)
+	regexpLetter      = regexp.MustCompile(`[A-Za-z]`)
+	regexpHunkHeader  = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+)
+
+// secretFinding 是一处疑似密钥，Line 是新文件中的行号，删除的行使用旧文件中的行号
+type secretFinding struct {
+	File    string
+	Line    int
+	Removed bool
+	Rule    string
+}
+
+func (f secretFinding) String() string {
+	loc := fmt.Sprintf("%s:%d", f.File, f.Line)
+	if f.Removed {
+		loc += " (removed line)"
+	}
+	return fmt.Sprintf("%s: %s", loc, f.Rule)
+}
+
+// secretsMode 返回密钥检测的模式，优先使用 --secrets，其次是 ai.secrets.mode
+func secretsMode() string {
+	if secretsFlag != "" {
+		return secretsFlag
+	}
+	if config.AI.Secrets.Mode != "" {
+		return config.AI.Secrets.Mode
+	}
+	return secretsRedact
+}
+
+// secretRules 返回内置规则和 ai.secrets.patterns 中的自定义规则
+func secretRules() ([]secretRule, error) {
+	rules := defaultSecretRules
+	for i, pattern := range config.AI.Secrets.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ai.secrets.patterns[%d] %q: %w", i, pattern, err)
+		}
+		rules = append(rules, secretRule{Name: "custom-" + strconv.Itoa(i+1), Pattern: re})
+	}
+	return rules, nil
+}
+
+// checkSecrets 按 secretsMode 处理 diff 中的密钥：redact 时替换并提示位置，block 时返回包含所有位置的错误，
+// quiet 为 true 时只替换，不再提示已经提示过的位置
+func checkSecrets(files []fileDiff, quiet bool) error {
+	mode := secretsMode()
+	if mode == secretsOff {
+		return nil
+	}
+	if !slices.Contains(secretsModes, mode) {
+		return fmt.Errorf("invalid secrets mode: %s (available: %s)", mode, strings.Join(secretsModes, "|"))
+	}
+	rules, err := secretRules()
+	if err != nil {
+		return err
+	}
+	findings := scanSecrets(files, rules)
+	if len(findings) == 0 {
+		return nil
+	}
+	locations := make([]string, len(findings))
+	for i, f := range findings {
+		locations[i] = "  " + f.String()
+	}
+	if mode == secretsBlock {
+		return fmt.Errorf("possible secrets found in the staged diff, nothing was sent to AI:\n%s", strings.Join(locations, "\n"))
+	}
+	if !quiet {
+		warningLog("Possible secrets redacted before sending the diff to AI:\n%s", strings.Join(locations, "\n"))
+	}
+	return nil
+}
+
+// redactSecrets 按 secretsMode 检查 diff 之外发送给 AI 的内容，例如冲突块
+func redactSecrets(file, text string) (string, error) {
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		lines[i] = " " + lines[i]
+	}
+	files := []fileDiff{{Path: file, Hunks: []string{fmt.Sprintf("@@ -1,%d +1,%d @@\n", len(lines), len(lines)) + strings.Join(lines, "\n")}}}
+	if err := checkSecrets(files, false); err != nil {
+		return "", err
+	}
+	lines = strings.Split(strings.SplitN(files[0].Hunks[0], "\n", 2)[1], "\n")
+	for i := range lines {
+		lines[i] = strings.TrimPrefix(lines[i], " ")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// isEnvFile 判断是否为 .env 文件，.env.example 这类示例文件除外
+func isEnvFile(file string) bool {
+	base := path.Base(file)
+	if base != ".env" && !strings.HasPrefix(base, ".env.") {
+		return false
+	}
+	for _, suffix := range []string{".example", ".sample", ".template", ".dist"} {
+		if strings.HasSuffix(base, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanSecrets 检查即将发送给 AI 的 diff，返回所有疑似密钥的位置，并在 files 中将它们替换为 [REDACTED:规则名]。
+// 私钥会连同整个密钥块一起替换，.env 文件中的每个值都会被替换
+func scanSecrets(files []fileDiff, rules []secretRule) []secretFinding {
+	var findings []secretFinding
+	for fi := range files {
+		f := &files[fi]
+		envFile := isEnvFile(f.Path)
+		for hi, hunk := range f.Hunks {
+			lines := strings.Split(hunk, "\n")
+			var oldLine, newLine int
+			inKey := false
+			for li, line := range lines {
+				if m := regexpHunkHeader.FindStringSubmatch(line); m != nil {
+					oldLine, _ = strconv.Atoi(m[1])
+					newLine, _ = strconv.Atoi(m[2])
+					continue
+				}
+				if line == "" || !strings.ContainsRune("+- ", rune(line[0])) {
+					continue
+				}
+				sign, content := line[:1], line[1:]
+				finding := secretFinding{File: f.Path, Line: newLine, Removed: sign == "-"}
+				if finding.Removed {
+					finding.Line = oldLine
+					oldLine++
+				} else {
+					if sign == " " {
+						oldLine++
+					}
+					newLine++
+				}
+				redacted, rule := redactSecretLine(content, rules, envFile, &inKey)
+				if rule != "" {
+					finding.Rule = rule
+					findings = append(findings, finding)
+					lines[li] = sign + redacted
+				}
+			}
+			f.Hunks[hi] = strings.Join(lines, "\n")
+		}
+	}
+	return findings
+}
+
+// redactSecretLine 替换一行中的密钥，返回替换后的内容和命中的规则名，未命中时规则名为空
+func redactSecretLine(content string, rules []secretRule, envFile bool, inKey *bool) (string, string) {
+	if *inKey {
+		if strings.Contains(content, "PRIVATE KEY") && strings.Contains(content, "-----END") {
+			*inKey = false
+		}
+		return "[REDACTED:private-key]", "private-key"
+	}
+	var matched []string
+	match := func(name string) {
+		if !slices.Contains(matched, name) {
+			matched = append(matched, name)
+		}
+	}
+	for _, rule := range rules {
+		if !rule.Pattern.MatchString(content) {
+			continue
+		}
+		if rule.Name == "private-key" {
+			*inKey = !strings.Contains(content, "-----END")
+			return "[REDACTED:private-key]", rule.Name
+		}
+		content = rule.Pattern.ReplaceAllString(content, "[REDACTED:"+rule.Name+"]")
+		match(rule.Name)
+	}
+	if envFile {
+		if m := regexpEnvLine.FindStringSubmatch(content); m != nil {
+			content = m[1] + "[REDACTED:env]"
+			match("env")
+		}
+	}
+	content = regexpSecretToken.ReplaceAllStringFunc(content, func(token string) string {
+		// 只检查同时包含字母和数字的片段，避免把较长的标识符当作密钥
+		if strings.Contains(token, "REDACTED") || !strings.ContainsAny(token, "0123456789") ||
+			!regexpLetter.MatchString(token) || shannonEntropy(token) < 4.5 {
+			return token
+		}
+		match("high-entropy")
+		return "[REDACTED:high-entropy]"
+	})
+	return content, strings.Join(matched, ", ")
+}
+
+// shannonEntropy 计算每个字符的信息熵，随机生成的 base64 密钥通常大于 4.5，十六进制的哈希最多为 4
+func shannonEntropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+	var entropy float64
+	n := float64(len(s))
+	for _, c := range counts {
+		p := float64(c) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
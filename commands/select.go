@@ -13,6 +13,7 @@ var SelectCmd = &cobra.Command{
 	Use:   "select",
 	Short: "Select common projects to clone, like `gitx select` or `gitx select -b main`",
 	Run: func(cmd *cobra.Command, args []string) {
+		resolveMainBranch(cmd, &mainBranch)
 		prompt := promptui.Select{
 			Label: "Select Repository to Clone",
 			Items: config.CommonProjects,
@@ -41,7 +41,7 @@ var SyncCmd = &cobra.Command{
 		execCommand("git", "checkout", branch)
 		execCommand("git", "pull", "origin", branch)
 		// merge feat branch into target branch
-		execCommand("git", "merge", "--no-ff", "-m",
+		mergeBranch(cmd, "--no-ff", "-m",
 			fmt.Sprintf("[Branch Merge] Merge %s into %s", version, branch), version)
 		execCommand("git", "push", "--set-upstream", "origin", branch)
 		execCommand("git", "checkout", version)
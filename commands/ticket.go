@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const defaultTicketTrailer = "Refs"
+
+// ticketTrailers 是允许写入的 trailer
+var ticketTrailers = []string{"Refs", "Closes"}
+
+// ticketKeyDenylist 是默认正则会误匹配的编码、算法和标准名称，例如 UTF-8、SHA-256、ISO-8601
+var ticketKeyDenylist = []string{"UTF", "SHA", "ISO", "RFC", "CVE"}
+
+// ticketRegexp 返回提取 issue 编号的正则，未配置 am.ticket_pattern 时匹配 Jira 风格的 ABC-123
+func ticketRegexp() (*regexp.Regexp, error) {
+	if config.AM.TicketPattern == "" {
+		return regexpTicket, nil
+	}
+	re, err := regexp.Compile(config.AM.TicketPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid am.ticket_pattern %q: %w", config.AM.TicketPattern, err)
+	}
+	return re, nil
+}
+
+// ticketIDs 从分支名中提取去重后的 issue 编号，正则中有分组时使用第一个分组，
+// 例如 GitLab 的分支 "123-fix-login" 可以配置为 `^(\d+)-`
+func ticketIDs(branch string) ([]string, error) {
+	re, err := ticketRegexp()
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, m := range re.FindAllStringSubmatch(branch, -1) {
+		id := m[0]
+		if re == regexpTicket {
+			// 默认正则的分组只用于排除 ticketKeyDenylist，编号仍然是整个匹配
+			if slices.Contains(ticketKeyDenylist, m[1]) {
+				continue
+			}
+		} else if len(m) > 1 && m[1] != "" {
+			id = m[1]
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// commitTickets 返回本次提交的 issue 编号，--ticket 优先于分支名，
+// 开启 am.require_ticket 且没有编号时返回错误
+func commitTickets(data *promptData) ([]string, error) {
+	if len(ticketIDFlags) > 0 {
+		data.Ticket = ticketIDFlags[0]
+		return ticketIDFlags, nil
+	}
+	tickets, err := ticketIDs(data.Branch)
+	if err != nil {
+		return nil, err
+	}
+	if len(tickets) == 0 && config.AM.RequireTicket {
+		return nil, fmt.Errorf("no ticket found in branch %s, use --ticket or a branch name matching am.ticket_pattern", data.Branch)
+	}
+	return tickets, nil
+}
+
+// ticketTrailer 返回 am.ticket_trailer，默认 Refs
+func ticketTrailer() string {
+	if config.AM.TicketTrailer != "" {
+		return config.AM.TicketTrailer
+	}
+	return defaultTicketTrailer
+}
+
+// ticketTrailerValue 生成 trailer 的值，纯数字的编号按 GitLab/GitHub 的写法加上 #
+func ticketTrailerValue(ids []string) string {
+	refs := make([]string, len(ids))
+	for i, id := range ids {
+		if strings.Trim(id, "0123456789") == "" {
+			id = "#" + id
+		}
+		refs[i] = id
+	}
+	return strings.Join(refs, ", ")
+}
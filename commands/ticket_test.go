@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTicketIDs(t *testing.T) {
+	tests := []struct {
+		pattern, branch string
+		want            []string
+	}{
+		{"", "feat-3.4.0-PROJ-42-login", []string{"PROJ-42"}},
+		{"", "PROJ-42-and-OPS-7-PROJ-42", []string{"PROJ-42", "OPS-7"}},
+		{"", "feature/UTF-8-support", nil},
+		{"", "fix/SHA-256-and-ISO-8601", nil},
+		{"", "fix/RFC-3339-dates-CORE-12", []string{"CORE-12"}},
+		{"", "feature/X-1-single-letter", nil},
+		{"", "fix/PROJ-0-zero", nil},
+		{"", "fix/MYPROJ-42abc", nil},
+		{"", "main", nil},
+		{`^(\d+)-`, "123-fix-login", []string{"123"}},
+		{`[a-z]+-\d+`, "feature/utf-8", []string{"utf-8"}},
+	}
+	t.Cleanup(func() { config.AM.TicketPattern = "" })
+	for _, tt := range tests {
+		config.AM.TicketPattern = tt.pattern
+		got, err := ticketIDs(tt.branch)
+		if err != nil {
+			t.Fatalf("ticketIDs(%q) error: %v", tt.branch, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ticketIDs(%q) with pattern %q = %q, want %q", tt.branch, tt.pattern, got, tt.want)
+		}
+	}
+	config.AM.TicketPattern = "["
+	if _, err := ticketIDs("PROJ-1"); err == nil {
+		t.Error("ticketIDs with an invalid am.ticket_pattern expected error")
+	}
+}
@@ -1,12 +1,14 @@
 package commands
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"os/user"
 	"path"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 
@@ -16,7 +18,7 @@ import (
 var (
 	regexpGitRepo    = regexp.MustCompile(`git@[^:]+:([^\.]+).git$`)
 	regexpSplitSpace = regexp.MustCompile(`\s+`)
-	regexpSplitter   = regexp.MustCompile(`(feat:|test:|revert:|chore:|style:|refactor:|fix:|\n|\r)`)
+	regexpTicket     = regexp.MustCompile(`\b([A-Z][A-Z0-9]{1,9})-[1-9]\d*\b`)
 )
 
 var (
@@ -98,6 +100,25 @@ func execCommandWithOutput(name string, args ...string) string {
 	return strings.TrimSpace(string(data))
 }
 
+// runCommand 执行命令并返回输出，不打印输出也不退出，由调用方处理错误
+func runCommand(name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	data, err := cmd.CombinedOutput()
+	if isDebug {
+		log.Println(cmd.String())
+	}
+	output := strings.TrimSpace(string(data))
+	if err != nil {
+		return output, fmt.Errorf("%s [%s]: %s", err.Error(), cmd.String(), output)
+	}
+	return output, nil
+}
+
+// splitNull 拆分 git -z 输出的文件列表，非 ASCII 和特殊字符的路径不会被加上引号和转义
+func splitNull(output string) []string {
+	return slices.DeleteFunc(strings.Split(output, "\x00"), func(s string) bool { return s == "" })
+}
+
 func commandExists(name string) bool {
 	_, err := exec.LookPath(name)
 	return err == nil
@@ -29,6 +29,13 @@ func main() {
 	rootCmd.AddCommand(commands.MergeBackCmd)
 	rootCmd.AddCommand(commands.UseCmd)
 	rootCmd.AddCommand(commands.DocCmd)
+	rootCmd.AddCommand(commands.CacheCmd)
+	rootCmd.AddCommand(commands.AICmd)
+	rootCmd.AddCommand(commands.HookCmd)
+	rootCmd.AddCommand(commands.MRCmd)
+	rootCmd.AddCommand(commands.ReviewCmd)
+	rootCmd.AddCommand(commands.ChangelogCmd)
+	commands.BindConfigFlags(rootCmd)
 	rootCmd.Version = version
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatalln("Execute rootCmd fail:", err)